@@ -1,27 +1,95 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/alexandreLamarre/pprof-server/pkg/scrape"
 	"github.com/alexandreLamarre/pprof-server/pkg/server"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/disk"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
 	"github.com/samber/lo"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colprofilespb "go.opentelemetry.io/proto/otlp/collector/profiles/v1experimental"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
 )
 
+func buildStore(
+	driver, dataDir string,
+	memLimit int64,
+	retention storage.RetentionPolicies,
+) (storage.ProfileStore, func() error, error) {
+	switch driver {
+	case "mem":
+		store := mem.NewProfileMemStorage(
+			mem.WithMemoryLimit(memLimit),
+			mem.WithRetention(retention),
+		)
+		return store, func() error {
+			if evicted := store.(storage.EvictionReporter).Evicted(); evicted.Profiles > 0 {
+				logrus.Infof("evicted %d profiles (%d bytes) to stay within the memory limit", evicted.Profiles, evicted.Bytes)
+			}
+			return nil
+		}, nil
+	case "disk":
+		opts := disk.DefaultOptions()
+		opts.Retention = retention
+		store, err := disk.NewProfileDiskStorage(dataDir, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %s", driver)
+	}
+}
+
 func BuildPprofServer() *cobra.Command {
 	var grpcAddr string
 	var httpAddr string
+	var storageDriver string
+	var dataDir string
+	var memLimit int64
+	var compactionInterval time.Duration
+	var retentionPolicies []string
+	var scrapeTargets []string
+	var scrapeTargetsFile string
+	scrapeOpts := scrape.DefaultOptions()
+	var uiCacheSize int
+	var uiCacheTTL time.Duration
 	cmd := &cobra.Command{
 		Use: "pprofserver",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			retention := storage.DefaultRetentionPolicies()
+			for _, spec := range retentionPolicies {
+				if err := retention.ParseRetentionPolicy(spec); err != nil {
+					return err
+				}
+			}
+			store, closeStore, err := buildStore(storageDriver, dataDir, memLimit, retention)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := closeStore(); err != nil {
+					logrus.Errorf("failed to close storage: %v", err)
+				}
+			}()
+			if compactor, ok := store.(storage.Compactor); ok && compactionInterval > 0 {
+				go storage.RunCompaction(cmd.Context(), compactor, compactionInterval)
+			}
 
 			gListener, err := net.Listen("tcp4", grpcAddr)
 			if err != nil {
@@ -38,28 +106,53 @@ func BuildPprofServer() *cobra.Command {
 					Timeout: 5 * time.Second,
 				}),
 				grpc.StatsHandler(otelgrpc.NewServerHandler()),
+				grpc.MaxRecvMsgSize(server.MaxGRPCMessageSize),
 			)
 
-			pprofServer := server.NewPprofServer()
+			pprofServer := server.NewPprofServer(store)
+			if len(scrapeTargets) > 0 || scrapeTargetsFile != "" {
+				targets := []scrape.Target{}
+				for _, spec := range scrapeTargets {
+					target, err := scrape.ParseTarget(spec)
+					if err != nil {
+						return err
+					}
+					targets = append(targets, target)
+				}
+				scraper, err := scrape.NewScraper(pprofServer, targets, scrapeTargetsFile, scrapeOpts)
+				if err != nil {
+					return err
+				}
+				go scraper.Run(cmd.Context())
+			}
 			grpcServer.RegisterService(&collogspb.LogsService_ServiceDesc, pprofServer)
 			grpcServer.RegisterService(&db.DB_ServiceDesc, pprofServer)
+			grpcServer.RegisterService(&colprofilespb.ProfilesService_ServiceDesc, pprofServer.ProfilesServer())
 
 			errGC := lo.Async(func() error {
 				logrus.Infof("Pprof gRPC server listening on %s....", grpcAddr)
 				return grpcServer.Serve(gListener)
 			})
-			var httpServer *server.PprofHttpServer
+			// the HTTP server is created upfront so shutting down never races with its creation
+			conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(
+				insecure.NewCredentials(),
+			), grpc.WithDefaultCallOptions(
+				grpc.MaxCallSendMsgSize(server.MaxGRPCMessageSize),
+				grpc.MaxCallRecvMsgSize(server.MaxGRPCMessageSize),
+			))
+			if err != nil {
+				return err
+			}
+			dbClient := db.NewDBClient(conn)
+			logsClient := collogspb.NewLogsServiceClient(conn)
+			httpServer := server.NewHttpServer(
+				httpAddr,
+				dbClient,
+				logsClient,
+				server.WithHandlerCache(uiCacheSize, uiCacheTTL),
+			)
 			errHC := lo.Async(func() error {
-
-				conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(
-					insecure.NewCredentials(),
-				))
-				if err != nil {
-					return err
-				}
-				dbClient := db.NewDBClient(conn)
 				logrus.Infof("Pprof HTTP server listening on %s....", httpAddr)
-				httpServer = server.NewHttpServer(httpAddr, dbClient)
 				return httpServer.ListenAndServe()
 			})
 
@@ -81,10 +174,25 @@ func BuildPprofServer() *cobra.Command {
 	}
 	cmd.Flags().StringVarP(&httpAddr, "http-addr", "a", ":10000", "The address to listen on for HTTP requests.")
 	cmd.Flags().StringVarP(&grpcAddr, "grpc-addr", "g", ":10001", "The address to listen on for GRPC requests.")
+	cmd.Flags().StringVar(&storageDriver, "storage", "mem", "The storage driver to use for profiles, one of : mem, disk.")
+	cmd.Flags().Int64Var(&memLimit, "mem-limit", 0, "The approximate number of bytes the mem storage driver may use before evicting the oldest profiles, 0 is unbounded.")
+	cmd.Flags().StringVar(&dataDir, "data-dir", "data", "The directory the disk storage driver persists profiles to.")
+	cmd.Flags().DurationVar(&compactionInterval, "compaction-interval", 5*time.Minute, "How often stored profiles are compacted, 0 disables compaction.")
+	cmd.Flags().StringArrayVar(&retentionPolicies, "retention-policy", []string{}, "Retention policy of the form [<profileType>=]<after>:<resolution>,...,retain:<duration>, e.g. cpu=1h:1m,24h:1h,retain:720h. Can be repeated.")
+	cmd.Flags().IntVar(&uiCacheSize, "ui-cache-size", 32, "The number of merged profiles the web UI keeps in memory between requests, 0 disables caching.")
+	cmd.Flags().DurationVar(&uiCacheTTL, "ui-cache-ttl", 5*time.Minute, "How long the web UI keeps a merged profile in memory when no new profiles arrive.")
+	cmd.Flags().StringArrayVar(&scrapeTargets, "scrape-target", []string{}, "Target serving /debug/pprof to scrape, of the form [<id>=]<endpoint>, e.g. api=localhost:6060. Can be repeated.")
+	cmd.Flags().StringVar(&scrapeTargetsFile, "scrape-targets-file", "", "JSON file listing targets to scrape, re-read every scrape interval, e.g. [{\"id\": \"api\", \"endpoint\": \"localhost:6060\", \"labels\": {\"env\": \"prod\"}}].")
+	cmd.Flags().DurationVar(&scrapeOpts.Interval, "scrape-interval", scrapeOpts.Interval, "How often targets are scraped.")
+	cmd.Flags().IntVar(&scrapeOpts.ProfileSeconds, "scrape-cpu-seconds", scrapeOpts.ProfileSeconds, "Duration of scraped cpu profiles in seconds.")
+	cmd.Flags().StringSliceVar(&scrapeOpts.Profiles, "scrape-profiles", scrapeOpts.Profiles, "The /debug/pprof profiles to scrape.")
 	return cmd
 }
 
 func main() {
+	// cancelling the context on shutdown stops the servers and closes the store
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	cmd := BuildPprofServer()
-	cmd.Execute()
+	cmd.ExecuteContext(ctx)
 }
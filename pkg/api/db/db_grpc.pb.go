@@ -19,14 +19,32 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	DB_Get_FullMethodName = "/db.DB/Get"
+	DB_Put_FullMethodName              = "/db.DB/Put"
+	DB_Get_FullMethodName              = "/db.DB/Get"
+	DB_GetStream_FullMethodName        = "/db.DB/GetStream"
+	DB_ListInstances_FullMethodName    = "/db.DB/ListInstances"
+	DB_ListProfileTypes_FullMethodName = "/db.DB/ListProfileTypes"
+	DB_Top_FullMethodName              = "/db.DB/Top"
+	DB_FunctionSeries_FullMethodName   = "/db.DB/FunctionSeries"
+	DB_Compare_FullMethodName          = "/db.DB/Compare"
+	DB_PGO_FullMethodName              = "/db.DB/PGO"
+	DB_Delete_FullMethodName           = "/db.DB/Delete"
 )
 
 // DBClient is the client API for DB service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type DBClient interface {
+	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error)
 	Get(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*GetProfileResponse, error)
+	GetStream(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (DB_GetStreamClient, error)
+	ListInstances(ctx context.Context, in *ListInstancesRequest, opts ...grpc.CallOption) (*ListInstancesResponse, error)
+	ListProfileTypes(ctx context.Context, in *ListProfileTypesRequest, opts ...grpc.CallOption) (*ListProfileTypesResponse, error)
+	Top(ctx context.Context, in *TopRequest, opts ...grpc.CallOption) (*TopResponse, error)
+	FunctionSeries(ctx context.Context, in *FunctionSeriesRequest, opts ...grpc.CallOption) (*FunctionSeriesResponse, error)
+	Compare(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error)
+	PGO(ctx context.Context, in *PGORequest, opts ...grpc.CallOption) (*GetProfileResponse, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
 }
 
 type dBClient struct {
@@ -37,6 +55,15 @@ func NewDBClient(cc grpc.ClientConnInterface) DBClient {
 	return &dBClient{cc}
 }
 
+func (c *dBClient) Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*PutResponse, error) {
+	out := new(PutResponse)
+	err := c.cc.Invoke(ctx, DB_Put_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *dBClient) Get(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (*GetProfileResponse, error) {
 	out := new(GetProfileResponse)
 	err := c.cc.Invoke(ctx, DB_Get_FullMethodName, in, out, opts...)
@@ -46,20 +73,151 @@ func (c *dBClient) Get(ctx context.Context, in *GetProfileRequest, opts ...grpc.
 	return out, nil
 }
 
+func (c *dBClient) GetStream(ctx context.Context, in *GetProfileRequest, opts ...grpc.CallOption) (DB_GetStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DB_ServiceDesc.Streams[0], DB_GetStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &dBGetStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DB_GetStreamClient interface {
+	Recv() (*GetProfileResponse, error)
+	grpc.ClientStream
+}
+
+type dBGetStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *dBGetStreamClient) Recv() (*GetProfileResponse, error) {
+	m := new(GetProfileResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *dBClient) ListInstances(ctx context.Context, in *ListInstancesRequest, opts ...grpc.CallOption) (*ListInstancesResponse, error) {
+	out := new(ListInstancesResponse)
+	err := c.cc.Invoke(ctx, DB_ListInstances_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dBClient) ListProfileTypes(ctx context.Context, in *ListProfileTypesRequest, opts ...grpc.CallOption) (*ListProfileTypesResponse, error) {
+	out := new(ListProfileTypesResponse)
+	err := c.cc.Invoke(ctx, DB_ListProfileTypes_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dBClient) Top(ctx context.Context, in *TopRequest, opts ...grpc.CallOption) (*TopResponse, error) {
+	out := new(TopResponse)
+	err := c.cc.Invoke(ctx, DB_Top_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dBClient) FunctionSeries(ctx context.Context, in *FunctionSeriesRequest, opts ...grpc.CallOption) (*FunctionSeriesResponse, error) {
+	out := new(FunctionSeriesResponse)
+	err := c.cc.Invoke(ctx, DB_FunctionSeries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dBClient) Compare(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error) {
+	out := new(CompareResponse)
+	err := c.cc.Invoke(ctx, DB_Compare_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dBClient) PGO(ctx context.Context, in *PGORequest, opts ...grpc.CallOption) (*GetProfileResponse, error) {
+	out := new(GetProfileResponse)
+	err := c.cc.Invoke(ctx, DB_PGO_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dBClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, DB_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DBServer is the server API for DB service.
 // All implementations should embed UnimplementedDBServer
 // for forward compatibility
 type DBServer interface {
+	Put(context.Context, *PutRequest) (*PutResponse, error)
 	Get(context.Context, *GetProfileRequest) (*GetProfileResponse, error)
+	GetStream(*GetProfileRequest, DB_GetStreamServer) error
+	ListInstances(context.Context, *ListInstancesRequest) (*ListInstancesResponse, error)
+	ListProfileTypes(context.Context, *ListProfileTypesRequest) (*ListProfileTypesResponse, error)
+	Top(context.Context, *TopRequest) (*TopResponse, error)
+	FunctionSeries(context.Context, *FunctionSeriesRequest) (*FunctionSeriesResponse, error)
+	Compare(context.Context, *CompareRequest) (*CompareResponse, error)
+	PGO(context.Context, *PGORequest) (*GetProfileResponse, error)
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
 }
 
 // UnimplementedDBServer should be embedded to have forward compatible implementations.
 type UnimplementedDBServer struct {
 }
 
+func (UnimplementedDBServer) Put(context.Context, *PutRequest) (*PutResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
+}
 func (UnimplementedDBServer) Get(context.Context, *GetProfileRequest) (*GetProfileResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
 }
+func (UnimplementedDBServer) GetStream(*GetProfileRequest, DB_GetStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetStream not implemented")
+}
+func (UnimplementedDBServer) ListInstances(context.Context, *ListInstancesRequest) (*ListInstancesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInstances not implemented")
+}
+func (UnimplementedDBServer) ListProfileTypes(context.Context, *ListProfileTypesRequest) (*ListProfileTypesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProfileTypes not implemented")
+}
+func (UnimplementedDBServer) Top(context.Context, *TopRequest) (*TopResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Top not implemented")
+}
+func (UnimplementedDBServer) FunctionSeries(context.Context, *FunctionSeriesRequest) (*FunctionSeriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FunctionSeries not implemented")
+}
+func (UnimplementedDBServer) Compare(context.Context, *CompareRequest) (*CompareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Compare not implemented")
+}
+func (UnimplementedDBServer) PGO(context.Context, *PGORequest) (*GetProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PGO not implemented")
+}
+func (UnimplementedDBServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
 
 // UnsafeDBServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to DBServer will
@@ -72,6 +230,24 @@ func RegisterDBServer(s grpc.ServiceRegistrar, srv DBServer) {
 	s.RegisterService(&DB_ServiceDesc, srv)
 }
 
+func _DB_Put_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DBServer).Put(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DB_Put_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DBServer).Put(ctx, req.(*PutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _DB_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetProfileRequest)
 	if err := dec(in); err != nil {
@@ -90,6 +266,153 @@ func _DB_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{})
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DB_GetStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetProfileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DBServer).GetStream(m, &dBGetStreamServer{stream})
+}
+
+type DB_GetStreamServer interface {
+	Send(*GetProfileResponse) error
+	grpc.ServerStream
+}
+
+type dBGetStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *dBGetStreamServer) Send(m *GetProfileResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DB_ListInstances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInstancesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DBServer).ListInstances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DB_ListInstances_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DBServer).ListInstances(ctx, req.(*ListInstancesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DB_ListProfileTypes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProfileTypesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DBServer).ListProfileTypes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DB_ListProfileTypes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DBServer).ListProfileTypes(ctx, req.(*ListProfileTypesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DB_Top_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DBServer).Top(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DB_Top_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DBServer).Top(ctx, req.(*TopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DB_FunctionSeries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FunctionSeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DBServer).FunctionSeries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DB_FunctionSeries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DBServer).FunctionSeries(ctx, req.(*FunctionSeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DB_Compare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DBServer).Compare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DB_Compare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DBServer).Compare(ctx, req.(*CompareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DB_PGO_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PGORequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DBServer).PGO(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DB_PGO_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DBServer).PGO(ctx, req.(*PGORequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DB_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DBServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DB_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DBServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DB_ServiceDesc is the grpc.ServiceDesc for DB service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -97,11 +420,49 @@ var DB_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "db.DB",
 	HandlerType: (*DBServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Put",
+			Handler:    _DB_Put_Handler,
+		},
 		{
 			MethodName: "Get",
 			Handler:    _DB_Get_Handler,
 		},
+		{
+			MethodName: "ListInstances",
+			Handler:    _DB_ListInstances_Handler,
+		},
+		{
+			MethodName: "ListProfileTypes",
+			Handler:    _DB_ListProfileTypes_Handler,
+		},
+		{
+			MethodName: "Top",
+			Handler:    _DB_Top_Handler,
+		},
+		{
+			MethodName: "FunctionSeries",
+			Handler:    _DB_FunctionSeries_Handler,
+		},
+		{
+			MethodName: "Compare",
+			Handler:    _DB_Compare_Handler,
+		},
+		{
+			MethodName: "PGO",
+			Handler:    _DB_PGO_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _DB_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetStream",
+			Handler:       _DB_GetStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "github.com/alexandreLamarre/pprof-server/pkg/api/db/db.proto",
 }
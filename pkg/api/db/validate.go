@@ -1,16 +1,147 @@
 package db
 
 import (
+	"regexp"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
 	codes "google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func (g *GetProfileRequest) Validate() error {
-	if g.InstanceId == "" {
+func (p *PutRequest) Validate() error {
+	if p.InstanceId == "" {
 		return status.Error(codes.InvalidArgument, "instanceId is required")
 	}
+	if p.Type == "" {
+		return status.Error(codes.InvalidArgument, "profileType is required")
+	}
+	if len(p.Data) == 0 {
+		return status.Error(codes.InvalidArgument, "data is required")
+	}
+	return nil
+}
+
+func (g *GetProfileRequest) Validate() error {
+	if g.InstanceId == "" && g.Selector == "" {
+		return status.Error(codes.InvalidArgument, "one of instanceId or selector is required")
+	}
+	if g.InstanceId != "" && g.Selector != "" {
+		return status.Error(codes.InvalidArgument, "instanceId and selector are mutually exclusive")
+	}
 	if g.Type == "" {
 		return status.Error(codes.InvalidArgument, "profileType is required")
 	}
+	if g.Base != nil && g.Base.InstanceId != "" && g.Base.Selector != "" {
+		return status.Error(codes.InvalidArgument, "base instanceId and selector are mutually exclusive")
+	}
+	return nil
+}
+
+func (l *ListProfileTypesRequest) Validate() error {
+	if l.InstanceId == "" {
+		return status.Error(codes.InvalidArgument, "instanceId is required")
+	}
+	return nil
+}
+
+func (t *TopRequest) Validate() error {
+	if t.InstanceId == "" && t.Selector == "" {
+		return status.Error(codes.InvalidArgument, "one of instanceId or selector is required")
+	}
+	if t.InstanceId != "" && t.Selector != "" {
+		return status.Error(codes.InvalidArgument, "instanceId and selector are mutually exclusive")
+	}
+	if t.Type == "" {
+		return status.Error(codes.InvalidArgument, "profileType is required")
+	}
+	if t.Limit < 0 {
+		return status.Error(codes.InvalidArgument, "limit must be positive")
+	}
+	if _, err := regexp.Compile(t.Focus); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid focus : %s", err)
+	}
+	if _, err := regexp.Compile(t.Ignore); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid ignore : %s", err)
+	}
+	return nil
+}
+
+func (f *FunctionSeriesRequest) Validate() error {
+	if f.InstanceId == "" {
+		return status.Error(codes.InvalidArgument, "instanceId is required")
+	}
+	if f.Type == "" {
+		return status.Error(codes.InvalidArgument, "profileType is required")
+	}
+	if f.Function == "" {
+		return status.Error(codes.InvalidArgument, "function is required")
+	}
+	if _, err := regexp.Compile(f.Function); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid function : %s", err)
+	}
+	if f.Step != nil && f.Step.AsDuration() <= 0 {
+		return status.Error(codes.InvalidArgument, "step must be positive")
+	}
+	return nil
+}
+
+func (c *CompareRequest) Validate() error {
+	if c.Type == "" {
+		return status.Error(codes.InvalidArgument, "profileType is required")
+	}
+	if c.Base == nil || c.Target == nil {
+		return status.Error(codes.InvalidArgument, "base and target are required")
+	}
+	if c.Base.InstanceId == "" && c.Base.Selector == "" {
+		return status.Error(codes.InvalidArgument, "one of base instanceId or selector is required")
+	}
+	if c.Base.InstanceId != "" && c.Base.Selector != "" {
+		return status.Error(codes.InvalidArgument, "base instanceId and selector are mutually exclusive")
+	}
+	if c.Target.InstanceId != "" && c.Target.Selector != "" {
+		return status.Error(codes.InvalidArgument, "target instanceId and selector are mutually exclusive")
+	}
+	if c.Limit < 0 {
+		return status.Error(codes.InvalidArgument, "limit must be positive")
+	}
+	return nil
+}
+
+func (p *PGORequest) Validate() error {
+	if p.InstanceId == "" && p.Selector == "" {
+		return status.Error(codes.InvalidArgument, "one of instanceId or selector is required")
+	}
+	if p.InstanceId != "" && p.Selector != "" {
+		return status.Error(codes.InvalidArgument, "instanceId and selector are mutually exclusive")
+	}
+	if p.Type == "" {
+		return status.Error(codes.InvalidArgument, "profileType is required")
+	}
+	if p.Threshold < 0 || p.Threshold >= 100 {
+		return status.Error(codes.InvalidArgument, "threshold must be a percentage between 0 and 100")
+	}
+	return nil
+}
+
+func (d *DeleteRequest) Validate() error {
+	if d.InstanceId == "" && d.Selector == "" {
+		return status.Error(codes.InvalidArgument, "one of instanceId or selector is required")
+	}
+	if d.InstanceId != "" && d.Selector != "" {
+		return status.Error(codes.InvalidArgument, "instanceId and selector are mutually exclusive")
+	}
+	if d.Selector != "" {
+		sel, err := storage.ParseSelector(d.Selector)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		// like prometheus series deletion, a selector must not match every instance
+		if sel.MatchesEmpty() {
+			return status.Errorf(codes.InvalidArgument, "selector %s matches every instance, at least one matcher must not match an empty value", sel)
+		}
+	}
+	if d.Start != nil && d.End != nil && d.End.AsTime().Before(d.Start.AsTime()) {
+		return status.Error(codes.InvalidArgument, "end must be after start")
+	}
 	return nil
 }
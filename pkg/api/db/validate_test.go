@@ -0,0 +1,41 @@
+package db
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDeleteRequestValidate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		req   *DeleteRequest
+		valid bool
+	}{
+		{name: "instance", req: &DeleteRequest{InstanceId: "a"}, valid: true},
+		{name: "selector", req: &DeleteRequest{Selector: `{env="prod"}`}, valid: true},
+		{name: "selector with empty matcher", req: &DeleteRequest{Selector: `{env="prod", region=""}`}, valid: true},
+		{name: "nothing", req: &DeleteRequest{}},
+		{name: "instance and selector", req: &DeleteRequest{InstanceId: "a", Selector: `{env="prod"}`}},
+		{name: "empty selector", req: &DeleteRequest{Selector: `{}`}},
+		{name: "matches empty value", req: &DeleteRequest{Selector: `{env=""}`}},
+		{name: "regexp matches empty value", req: &DeleteRequest{Selector: `{env=~".*"}`}},
+		{name: "negative matcher", req: &DeleteRequest{Selector: `{env!="prod"}`}},
+		{name: "invalid selector", req: &DeleteRequest{Selector: `{env=`}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.req.Validate()
+			if tc.valid {
+				if err != nil {
+					t.Errorf("expected valid request, got %s", err)
+				}
+				return
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultProfiles are the `/debug/pprof` endpoints scraped by default
+var DefaultProfiles = []string{"profile", "heap", "goroutine", "mutex", "block"}
+
+// Ingester stores scraped profiles
+type Ingester interface {
+	Ingest(ctx context.Context, instanceId, profileType string, metadata map[string]string, prof *profile.Profile) error
+}
+
+type Options struct {
+	// Interval between scrapes of a target
+	Interval time.Duration
+	// ProfileSeconds is the duration of cpu profiles, must be shorter than the interval
+	ProfileSeconds int
+	// Profiles are the `/debug/pprof` endpoints to scrape
+	Profiles []string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Interval:       time.Minute,
+		ProfileSeconds: 10,
+		Profiles:       DefaultProfiles,
+	}
+}
+
+// Scraper periodically pulls profiles from static targets and an optional targets file
+type Scraper struct {
+	ingester    Ingester
+	opts        Options
+	static      []Target
+	targetsFile string
+	client      *http.Client
+
+	// last successfully loaded targets from the targets file
+	fileTargets []Target
+}
+
+func NewScraper(ingester Ingester, static []Target, targetsFile string, opts Options) (*Scraper, error) {
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("scrape interval must be positive")
+	}
+	if time.Duration(opts.ProfileSeconds)*time.Second >= opts.Interval {
+		return nil, fmt.Errorf("cpu profile duration %ds must be shorter than the scrape interval %s", opts.ProfileSeconds, opts.Interval)
+	}
+	return &Scraper{
+		ingester:    ingester,
+		opts:        opts,
+		static:      static,
+		targetsFile: targetsFile,
+		client: &http.Client{
+			Timeout: opts.Interval,
+		},
+	}, nil
+}
+
+// targets returns the static targets & the current content of the targets file.
+// If the file can't be read, the previously loaded targets are kept.
+func (s *Scraper) targets() []Target {
+	if s.targetsFile != "" {
+		fileTargets, err := LoadTargets(s.targetsFile)
+		if err != nil {
+			logrus.Errorf("failed to load scrape targets, keeping %d previous targets : %s", len(s.fileTargets), err)
+		} else {
+			s.fileTargets = fileTargets
+		}
+	}
+	return append(append([]Target{}, s.static...), s.fileTargets...)
+}
+
+// Run scrapes every target each interval, blocks until ctx is done
+func (s *Scraper) Run(ctx context.Context) {
+	t := time.NewTicker(s.opts.Interval)
+	defer t.Stop()
+	for {
+		s.scrapeAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Scraper) scrapeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, target := range s.targets() {
+		for _, profileType := range s.opts.Profiles {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := s.scrape(ctx, target, profileType); err != nil {
+					logrus.Errorf("failed to scrape %s profile from %s : %s", profileType, target.Endpoint, err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func (s *Scraper) scrape(ctx context.Context, target Target, profileType string) error {
+	endpoint := target.Endpoint + "/debug/pprof/" + profileType
+	if profileType == "profile" {
+		endpoint += fmt.Sprintf("?seconds=%d", s.opts.ProfileSeconds)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	prof, err := profile.ParseData(data)
+	if err != nil {
+		return err
+	}
+	if err := prof.CheckValid(); err != nil {
+		return err
+	}
+
+	// tag profiles the same way the pprofreceiver's pprof_host & pprof_port attributes are
+	uri, err := url.Parse(target.Endpoint)
+	if err != nil {
+		return err
+	}
+	md := make(map[string]string, len(target.Labels)+2)
+	for k, v := range target.Labels {
+		md[k] = v
+	}
+	md["host"], md["port"] = hostPort(uri)
+	return s.ingester.Ingest(ctx, target.Id, profileType, md, prof)
+}
+
+// hostPort splits the host & port of an endpoint, defaulting to the port of its scheme
+func hostPort(uri *url.URL) (string, string) {
+	port := uri.Port()
+	if port == "" {
+		switch uri.Scheme {
+		case "https":
+			port = "443"
+		default:
+			port = "80"
+		}
+	}
+	return uri.Hostname(), port
+}
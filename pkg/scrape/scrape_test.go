@@ -0,0 +1,63 @@
+package scrape
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		id       string
+		endpoint string
+		err      bool
+	}{
+		{spec: "localhost:6060", id: "localhost:6060", endpoint: "http://localhost:6060"},
+		{spec: "api=localhost:6060/", id: "api", endpoint: "http://localhost:6060"},
+		{spec: "api=https://api.example.com", id: "api", endpoint: "https://api.example.com"},
+		{spec: "api=", err: true},
+		{spec: "http://", err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			target, err := ParseTarget(tc.spec)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error parsing %q", tc.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target.Id != tc.id || target.Endpoint != tc.endpoint {
+				t.Errorf("expected %s=%s, got %s=%s", tc.id, tc.endpoint, target.Id, target.Endpoint)
+			}
+		})
+	}
+}
+
+func TestHostPort(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		host     string
+		port     string
+	}{
+		{endpoint: "http://localhost:6060", host: "localhost", port: "6060"},
+		{endpoint: "http://api.example.com", host: "api.example.com", port: "80"},
+		{endpoint: "https://api.example.com", host: "api.example.com", port: "443"},
+		{endpoint: "http://[::1]:6060", host: "::1", port: "6060"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			uri, err := url.Parse(tc.endpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			host, port := hostPort(uri)
+			if host != tc.host || port != tc.port {
+				t.Errorf("expected %s %s, got %s %s", tc.host, tc.port, host, port)
+			}
+		})
+	}
+}
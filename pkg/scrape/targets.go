@@ -0,0 +1,70 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Target is a process serving the `net/http/pprof` handlers
+type Target struct {
+	// Id the scraped profiles are stored under, defaults to the endpoint's host:port
+	Id string `json:"id"`
+	// Endpoint is the base url of the target, e.g. http://localhost:6060
+	Endpoint string `json:"endpoint"`
+	// Labels are added to every profile scraped from the target
+	Labels map[string]string `json:"labels"`
+}
+
+func (t *Target) normalize() error {
+	if t.Endpoint == "" {
+		return fmt.Errorf("target endpoint must be set")
+	}
+	if !strings.Contains(t.Endpoint, "://") {
+		t.Endpoint = "http://" + t.Endpoint
+	}
+	t.Endpoint = strings.TrimSuffix(t.Endpoint, "/")
+	uri, err := url.Parse(t.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid target endpoint %s : %w", t.Endpoint, err)
+	}
+	if uri.Host == "" {
+		return fmt.Errorf("invalid target endpoint %s : missing host", t.Endpoint)
+	}
+	if t.Id == "" {
+		t.Id = uri.Host
+	}
+	return nil
+}
+
+// ParseTarget parses a static target of the form `[<id>=]<endpoint>`, e.g. `api=localhost:6060`
+func ParseTarget(spec string) (Target, error) {
+	t := Target{Endpoint: spec}
+	if id, endpoint, ok := strings.Cut(spec, "="); ok {
+		t.Id, t.Endpoint = id, endpoint
+	}
+	if err := t.normalize(); err != nil {
+		return Target{}, err
+	}
+	return t, nil
+}
+
+// LoadTargets reads a JSON list of targets, e.g. `[{"id": "api", "endpoint": "localhost:6060", "labels": {"env": "prod"}}]`
+func LoadTargets(path string) ([]Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	targets := []Target{}
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to parse targets file %s : %w", path, err)
+	}
+	for i := range targets {
+		if err := targets[i].normalize(); err != nil {
+			return nil, fmt.Errorf("invalid target in %s : %w", path, err)
+		}
+	}
+	return targets, nil
+}
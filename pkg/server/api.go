@@ -0,0 +1,261 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// downloadFormat describes how each output format of /api/profile is served
+type downloadFormat struct {
+	format      db.Format
+	contentType string
+	extension   string
+}
+
+var downloadFormats = map[string]downloadFormat{
+	"pprof":      {db.Format_FORMAT_PPROF, "application/octet-stream", ".pb.gz"},
+	"flamegraph": {db.Format_FORMAT_FLAMEGRAPH, "application/json", ".flamegraph.json"},
+	"speedscope": {db.Format_FORMAT_SPEEDSCOPE, "application/json", ".speedscope.json"},
+	"folded":     {db.Format_FORMAT_FOLDED, "text/plain; charset=utf-8", ".folded"},
+}
+
+// downloadProfile serves /api/profile?instance=<id>|selector=<selector>&type=<profile_type>[&from=<time>&to=<time>][&format=<format>&sample_type=<type>],
+// returning the merged profile as gzipped pprof by default, e.g. for `go tool pprof http://<addr>/api/profile?instance=api&type=cpu`
+func (p *PprofHttpServer) downloadProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	formatName := q.Get("format")
+	if formatName == "" {
+		formatName = "pprof"
+	}
+	format, ok := downloadFormats[formatName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown format %s, expected one of pprof, flamegraph, speedscope, folded", formatName), http.StatusBadRequest)
+		return
+	}
+	req, err := getRequestFromQuery(q, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Format = format.format
+	if err := req.Validate(); err != nil {
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGRPC(err))
+		return
+	}
+
+	data, err := p.getProfile(r.Context(), req)
+	if err != nil {
+		logrus.WithError(err).Error("failed to get profile")
+		writeGRPCError(w, "failed to get profile", err)
+		return
+	}
+
+	name := req.InstanceId
+	if name == "" {
+		name = "merged"
+	}
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", profileFilename(name, req.Type)+format.extension))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data)
+}
+
+// getRequestFromQuery reads the instance, selector, type, from, to, sample_type and base_* query parameters
+func getRequestFromQuery(q url.Values, now time.Time) (*db.GetProfileRequest, error) {
+	start, end, err := timeRangeFromQuery(q, "from", "to", now)
+	if err != nil {
+		return nil, err
+	}
+	base, err := baseFromQuery(q, now)
+	if err != nil {
+		return nil, err
+	}
+	return &db.GetProfileRequest{
+		InstanceId: q.Get("instance"),
+		Selector:   q.Get("selector"),
+		Type:       q.Get("type"),
+		Start:      start,
+		End:        end,
+		Base:       base,
+		SampleType: q.Get("sample_type"),
+	}, nil
+}
+
+// top serves /api/top?instance=<id>|selector=<selector>&type=<profile_type>[&from=<time>&to=<time>]
+// [&sample_type=<type>&focus=<regexp>&ignore=<regexp>&limit=<n>&sort=flat|cum], mirroring the Top RPC
+func (p *PprofHttpServer) top(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	get, err := getRequestFromQuery(q, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &db.TopRequest{
+		InstanceId: get.InstanceId,
+		Selector:   get.Selector,
+		Type:       get.Type,
+		Start:      get.Start,
+		End:        get.End,
+		SampleType: get.SampleType,
+		Focus:      q.Get("focus"),
+		Ignore:     q.Get("ignore"),
+	}
+	if limit := q.Get("limit"); limit != "" {
+		l, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit %q", limit), http.StatusBadRequest)
+			return
+		}
+		req.Limit = int32(l)
+	}
+	switch q.Get("sort") {
+	case "", "flat":
+	case "cum":
+		req.SortByCum = true
+	default:
+		http.Error(w, "sort must be one of flat, cum", http.StatusBadRequest)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGRPC(err))
+		return
+	}
+
+	resp, err := p.dbClient.Top(r.Context(), req)
+	if err != nil {
+		writeGRPCError(w, "failed to get top functions", err)
+		return
+	}
+	writeProtoJSON(w, resp)
+}
+
+// functionSeries serves /api/function-series?instance=<id>&type=<profile_type>&function=<regexp>
+// [&from=<time>&to=<time>&step=<duration>&sample_type=<type>], mirroring the FunctionSeries RPC
+func (p *PprofHttpServer) functionSeries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, end, err := timeRangeFromQuery(q, "from", "to", time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &db.FunctionSeriesRequest{
+		InstanceId: q.Get("instance"),
+		Type:       q.Get("type"),
+		Start:      start,
+		End:        end,
+		Function:   q.Get("function"),
+		SampleType: q.Get("sample_type"),
+	}
+	if step := q.Get("step"); step != "" {
+		d, err := time.ParseDuration(step)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid step %q", step), http.StatusBadRequest)
+			return
+		}
+		req.Step = durationpb.New(d)
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGRPC(err))
+		return
+	}
+
+	resp, err := p.dbClient.FunctionSeries(r.Context(), req)
+	if err != nil {
+		writeGRPCError(w, "failed to get function series", err)
+		return
+	}
+	writeProtoJSON(w, resp)
+}
+
+// pgo serves /api/pgo?instance=<id>|selector=<selector>&type=<profile_type>[&from=<time>&to=<time>&threshold=<percent>],
+// returning a profile ready to be saved as default.pgo
+func (p *PprofHttpServer) pgo(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	get, err := getRequestFromQuery(q, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req := &db.PGORequest{
+		InstanceId: get.InstanceId,
+		Selector:   get.Selector,
+		Type:       get.Type,
+		Start:      get.Start,
+		End:        get.End,
+	}
+	if threshold := q.Get("threshold"); threshold != "" {
+		t, err := strconv.ParseFloat(threshold, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid threshold %q", threshold), http.StatusBadRequest)
+			return
+		}
+		req.Threshold = t
+	}
+	if err := req.Validate(); err != nil {
+		http.Error(w, status.Convert(err).Message(), httpStatusFromGRPC(err))
+		return
+	}
+
+	data, err := p.dbClient.PGO(r.Context(), req)
+	if err != nil {
+		writeGRPCError(w, "failed to generate PGO profile", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="default.pgo"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(data.Data)
+}
+
+// profileFilename builds a filename safe name out of an instance id and profile type
+func profileFilename(name, profileType string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, name+"."+profileType)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithError(err).Error("failed to write JSON response")
+	}
+}
+
+// writeProtoJSON writes the JSON mapping of an RPC response, including zero values
+func writeProtoJSON(w http.ResponseWriter, msg proto.Message) {
+	data, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(msg)
+	if err != nil {
+		logrus.WithError(err).Error("failed to marshal JSON response")
+		http.Error(w, "failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// writeGRPCError writes the message of a gRPC status error with the matching HTTP status
+func writeGRPCError(w http.ResponseWriter, msg string, err error) {
+	http.Error(w, msg+" : "+status.Convert(err).Message(), httpStatusFromGRPC(err))
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// handlerCacheKey identifies the query a web UI handler was built for. Options of the pprof views
+// themselves, such as focus or the sample index, are handled by the web UI and aren't part of the key.
+type handlerCacheKey struct {
+	instanceId  string
+	profileType string
+	// raw from & to query parameters, relative ranges are re-evaluated once the entry expires
+	from, to string
+	// raw base_* query parameters
+	base string
+}
+
+// profileVersion changes whenever profiles are added to or removed from an instance's profile type
+type profileVersion struct {
+	count    int64
+	lastSeen time.Time
+}
+
+type cachedHandler struct {
+	key     handlerCacheKey
+	version profileVersion
+	handler http.Handler
+	expires time.Time
+}
+
+// handlerCache is an LRU cache of pprof web UI handlers, so navigating the views of
+// a profile doesn't fetch and merge it again on every request
+type handlerCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[handlerCacheKey]*list.Element
+	// front is most recently used
+	lru *list.List
+}
+
+func newHandlerCache(size int, ttl time.Duration) *handlerCache {
+	return &handlerCache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[handlerCacheKey]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func (c *handlerCache) enabled() bool {
+	return c.size > 0
+}
+
+// get returns the cached handler, dropping it when it expired or new data arrived since it was built
+func (c *handlerCache) get(key handlerCacheKey, version profileVersion, now time.Time) (http.Handler, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cachedHandler)
+	if now.After(entry.expires) || entry.version.count != version.count || !entry.version.lastSeen.Equal(version.lastSeen) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return entry.handler, true
+}
+
+func (c *handlerCache) add(key handlerCacheKey, version profileVersion, handler http.Handler, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &cachedHandler{
+		key:     key,
+		version: version,
+		handler: handler,
+		expires: now.Add(c.ttl),
+	}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedHandler).key)
+	}
+}
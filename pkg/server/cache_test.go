@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandlerCacheGet(t *testing.T) {
+	now := time.Now()
+	key := handlerCacheKey{instanceId: "api", profileType: "cpu", from: "now-15m"}
+	version := profileVersion{count: 3, lastSeen: now.Add(-time.Minute)}
+	testCases := []struct {
+		name    string
+		key     handlerCacheKey
+		version profileVersion
+		at      time.Time
+		hit     bool
+	}{
+		{
+			name:    "hit",
+			key:     key,
+			version: version,
+			at:      now.Add(time.Minute),
+			hit:     true,
+		},
+		{
+			name:    "expired",
+			key:     key,
+			version: version,
+			at:      now.Add(5*time.Minute + time.Second),
+		},
+		{
+			name:    "profiles added",
+			key:     key,
+			version: profileVersion{count: 4, lastSeen: now},
+			at:      now.Add(time.Minute),
+		},
+		{
+			name:    "profiles replaced",
+			key:     key,
+			version: profileVersion{count: 3, lastSeen: now},
+			at:      now.Add(time.Minute),
+		},
+		{
+			name:    "other time range",
+			key:     handlerCacheKey{instanceId: "api", profileType: "cpu", from: "now-1h"},
+			version: version,
+			at:      now.Add(time.Minute),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newHandlerCache(10, 5*time.Minute)
+			c.add(key, version, http.NotFoundHandler(), now)
+			if _, ok := c.get(tc.key, tc.version, tc.at); ok != tc.hit {
+				t.Fatalf("expected hit %t, got %t", tc.hit, ok)
+			}
+			if tc.hit {
+				return
+			}
+			// stale entries are dropped rather than kept until they are evicted
+			if tc.key == key && c.lru.Len() != 0 {
+				t.Errorf("expected the stale entry to be dropped, %d entries left", c.lru.Len())
+			}
+			if _, ok := c.get(key, version, now); ok != (tc.key != key) {
+				t.Errorf("unexpected cached entry after a miss: %t", ok)
+			}
+		})
+	}
+}
+
+func TestHandlerCacheEviction(t *testing.T) {
+	now := time.Now()
+	version := profileVersion{count: 1, lastSeen: now}
+	keys := []handlerCacheKey{
+		{instanceId: "a", profileType: "cpu"},
+		{instanceId: "b", profileType: "cpu"},
+		{instanceId: "c", profileType: "cpu"},
+	}
+	c := newHandlerCache(2, time.Minute)
+	c.add(keys[0], version, http.NotFoundHandler(), now)
+	c.add(keys[1], version, http.NotFoundHandler(), now)
+	// a becomes the most recently used entry, so b is evicted
+	if _, ok := c.get(keys[0], version, now); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	c.add(keys[2], version, http.NotFoundHandler(), now)
+
+	for key, cached := range map[handlerCacheKey]bool{keys[0]: true, keys[1]: false, keys[2]: true} {
+		if _, ok := c.get(key, version, now); ok != cached {
+			t.Errorf("expected %s to be cached: %t, got %t", key.instanceId, cached, ok)
+		}
+	}
+	if c.lru.Len() != len(c.entries) {
+		t.Errorf("lru list and entries out of sync : %d != %d", c.lru.Len(), len(c.entries))
+	}
+}
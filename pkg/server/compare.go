@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/google/pprof/profile"
+)
+
+func (p *PprofServer) Compare(ctx context.Context, req *db.CompareRequest) (*db.CompareResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	base, target := req.Base, req.Target
+	// the target defaults to the same instances as the base, over a different time range
+	targetId, targetSelector := target.InstanceId, target.Selector
+	if targetId == "" && targetSelector == "" {
+		targetId, targetSelector = base.InstanceId, base.Selector
+	}
+
+	baseProf, err := p.query(ctx, base.InstanceId, base.Selector, req.Type, base.Start, base.End)
+	if err != nil {
+		return nil, err
+	}
+	targetProf, err := p.query(ctx, targetId, targetSelector, req.Type, target.Start, target.End)
+	if err != nil {
+		return nil, err
+	}
+	baseIdx, err := sampleIndex(baseProf, req.SampleType)
+	if err != nil {
+		return nil, err
+	}
+	sampleType := baseProf.SampleType[baseIdx]
+	targetIdx, err := sampleIndex(targetProf, sampleType.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	baseRows, baseTotal := topFunctions(baseProf, baseIdx)
+	targetRows, targetTotal := topFunctions(targetProf, targetIdx)
+	baseSeconds, targetSeconds := profiledSeconds(baseProf), profiledSeconds(targetProf)
+
+	type key struct {
+		name, file string
+	}
+	value := func(r *db.TopRow) (int64, float64) {
+		if req.Cumulative {
+			return r.Cum, r.CumPercent
+		}
+		return r.Flat, r.FlatPercent
+	}
+	rows := map[key]*db.CompareRow{}
+	row := func(r *db.TopRow) *db.CompareRow {
+		k := key{r.Function, r.File}
+		if _, ok := rows[k]; !ok {
+			rows[k] = &db.CompareRow{
+				Function: r.Function,
+				File:     r.File,
+			}
+		}
+		return rows[k]
+	}
+	for _, r := range baseRows {
+		v, percent := value(r)
+		cr := row(r)
+		cr.BaseRate = float64(v) / baseSeconds
+		cr.BasePercent = percent
+	}
+	for _, r := range targetRows {
+		v, percent := value(r)
+		cr := row(r)
+		cr.TargetRate = float64(v) / targetSeconds
+		cr.TargetPercent = percent
+	}
+
+	ret := make([]*db.CompareRow, 0, len(rows))
+	for _, r := range rows {
+		r.Delta = r.TargetRate - r.BaseRate
+		if r.BaseRate != 0 {
+			r.RelativeDelta = r.Delta / r.BaseRate
+		}
+		r.PercentDelta = r.TargetPercent - r.BasePercent
+		ret = append(ret, r)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		di, dj := math.Abs(ret[i].PercentDelta), math.Abs(ret[j].PercentDelta)
+		if di != dj {
+			return di > dj
+		}
+		return ret[i].Function < ret[j].Function
+	})
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultTopLimit
+	}
+	if len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return &db.CompareResponse{
+		Rows:            ret,
+		SampleType:      sampleType.Type,
+		Unit:            sampleType.Unit,
+		BaseTotalRate:   float64(baseTotal) / baseSeconds,
+		TargetTotalRate: float64(targetTotal) / targetSeconds,
+	}, nil
+}
+
+// profiledSeconds is the time covered by a merged profile, merging sums the durations of profiles.
+// Profiles without a duration aren't normalized.
+func profiledSeconds(prof *profile.Profile) float64 {
+	if prof.DurationNanos <= 0 {
+		return 1
+	}
+	return time.Duration(prof.DurationNanos).Seconds()
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	"github.com/google/pprof/profile"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCompareNormalizesDuration(t *testing.T) {
+	type rates struct {
+		base, target float64
+	}
+	testCases := []struct {
+		name     string
+		duration time.Duration
+		totals   rates
+		rows     map[string]rates
+	}{
+		{
+			// the base window holds two 10s profiles, the target a single one
+			name:     "rates per profiled second",
+			duration: 10 * time.Second,
+			totals:   rates{base: 8.0 / 20, target: 8.0 / 10},
+			rows: map[string]rates{
+				"main.work": {base: 4.0 / 20, target: 2.0 / 10},
+				"main.wait": {base: 4.0 / 20, target: 6.0 / 10},
+			},
+		},
+		{
+			name:   "profiles without a duration aren't normalized",
+			totals: rates{base: 8, target: 8},
+			rows: map[string]rates{
+				"main.work": {base: 4, target: 2},
+				"main.wait": {base: 4, target: 6},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now().Truncate(time.Minute)
+			store := mem.NewProfileMemStorage()
+			put := func(at time.Time, values map[string]int64) {
+				prof := cpuProfile(at, values)
+				prof.DurationNanos = tc.duration.Nanoseconds()
+				if err := store.Put(ctx, "api", "cpu", nil, []*profile.Profile{prof}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			put(now.Add(-9*time.Minute), map[string]int64{"main.work": 2, "main.wait": 2})
+			put(now.Add(-8*time.Minute), map[string]int64{"main.work": 2, "main.wait": 2})
+			put(now.Add(-2*time.Minute), map[string]int64{"main.work": 2, "main.wait": 6})
+
+			resp, err := NewPprofServer(store).Compare(ctx, &db.CompareRequest{
+				Type:       "cpu",
+				SampleType: "samples",
+				Base: &db.CompareWindow{
+					InstanceId: "api",
+					Start:      timestamppb.New(now.Add(-10 * time.Minute)),
+					End:        timestamppb.New(now.Add(-5 * time.Minute)),
+				},
+				// defaults to the base's instance
+				Target: &db.CompareWindow{
+					Start: timestamppb.New(now.Add(-5 * time.Minute)),
+					End:   timestamppb.New(now),
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			approx := func(a, b float64) bool {
+				return math.Abs(a-b) < 1e-9
+			}
+			if !approx(resp.BaseTotalRate, tc.totals.base) || !approx(resp.TargetTotalRate, tc.totals.target) {
+				t.Errorf("expected total rates %v, got {%v %v}", tc.totals, resp.BaseTotalRate, resp.TargetTotalRate)
+			}
+			if len(resp.Rows) != len(tc.rows) {
+				t.Fatalf("expected %d rows, got %d", len(tc.rows), len(resp.Rows))
+			}
+			// main.wait's share grew from 50% to 75%, the tie is broken by name
+			if resp.Rows[0].Function != "main.wait" || !approx(resp.Rows[0].PercentDelta, 25) {
+				t.Errorf("expected main.wait to be the largest change, got %s %v", resp.Rows[0].Function, resp.Rows[0].PercentDelta)
+			}
+			for _, row := range resp.Rows {
+				expected := tc.rows[row.Function]
+				if !approx(row.BaseRate, expected.base) || !approx(row.TargetRate, expected.target) {
+					t.Errorf("expected %s rates %v, got {%v %v}", row.Function, expected, row.BaseRate, row.TargetRate)
+				}
+				if !approx(row.Delta, expected.target-expected.base) || !approx(row.RelativeDelta, (expected.target-expected.base)/expected.base) {
+					t.Errorf("unexpected %s deltas %v %v", row.Function, row.Delta, row.RelativeDelta)
+				}
+			}
+		})
+	}
+}
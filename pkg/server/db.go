@@ -1,11 +1,12 @@
 package server
 
 import (
-	"bytes"
 	"context"
 	"time"
 
 	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/google/pprof/profile"
 	"github.com/samber/lo"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -15,22 +16,129 @@ import (
 var _ db.DBServer = (*PprofServer)(nil)
 
 func (p *PprofServer) Get(ctx context.Context, req *db.GetProfileRequest) (*db.GetProfileResponse, error) {
+	data, err := p.get(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &db.GetProfileResponse{
+		Data: data,
+	}, nil
+}
+
+// getStreamChunkSize keeps streamed messages well below the default 4MB gRPC message size limit
+const getStreamChunkSize = 1 << 20
+
+func (p *PprofServer) GetStream(req *db.GetProfileRequest, stream db.DB_GetStreamServer) error {
+	data, err := p.get(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		n := min(len(data), getStreamChunkSize)
+		if err := stream.Send(&db.GetProfileResponse{
+			Data: data[:n],
+		}); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// get returns the encoded profile requested by Get & GetStream
+func (p *PprofServer) get(ctx context.Context, req *db.GetProfileRequest) ([]byte, error) {
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
-	startTime := lo.ToPtr(lo.FromPtrOr(req.Start, *timestamppb.New(time.Unix(0, 0)))).AsTime()
-	endTime := lo.ToPtr(lo.FromPtrOr(req.End, *timestamppb.New(time.Now()))).AsTime()
-	ret, err := p.store.Get(ctx, req.InstanceId, req.Type, startTime, endTime)
+	ret, err := p.query(ctx, req.InstanceId, req.Selector, req.Type, req.Start, req.End)
 	if err != nil {
 		return nil, err
 	}
-	b := bytes.NewBuffer([]byte{})
-	if err := ret.Write(b); err != nil { // note: this is compressed by default
-		return nil, status.Errorf(codes.Internal, "failed to write profile: %s to buffer", err)
+	if req.Base != nil {
+		// the base defaults to the same instances as the profile, over a different time range
+		instanceId, selector := req.InstanceId, req.Selector
+		if req.Base.InstanceId != "" || req.Base.Selector != "" {
+			instanceId, selector = req.Base.InstanceId, req.Base.Selector
+		}
+		base, err := p.query(ctx, instanceId, selector, req.Type, req.Base.Start, req.Base.End)
+		if err != nil {
+			return nil, err
+		}
+		ret, err = diffProfile(ret, base)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return &db.GetProfileResponse{
-		Data: b.Bytes(),
-	}, nil
+	return encodeProfile(ret, req.Format, req.SampleType)
+}
+
+// query merges the stored profiles of a single instance, or all instances matching the selector
+func (p *PprofServer) query(
+	ctx context.Context,
+	instanceId, selector, profileType string,
+	start, end *timestamppb.Timestamp,
+) (*profile.Profile, error) {
+	startTime := lo.ToPtr(lo.FromPtrOr(start, *timestamppb.New(time.Unix(0, 0)))).AsTime()
+	endTime := lo.ToPtr(lo.FromPtrOr(end, *timestamppb.New(time.Now()))).AsTime()
+	if selector != "" {
+		sel, err := storage.ParseSelector(selector)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return p.store.Select(ctx, sel, profileType, startTime, endTime)
+	}
+	return p.store.Get(ctx, instanceId, profileType, startTime, endTime)
+}
 
+// diffProfile subtracts the base from the profile, tagging base samples the same way `pprof -diff_base` does
+func diffProfile(prof, base *profile.Profile) (*profile.Profile, error) {
+	base.Scale(-1)
+	base.SetLabel("pprof::base", []string{"true"})
+	ret, err := profile.Merge([]*profile.Profile{prof, base})
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to diff profiles: %s, base is incompatible", err)
+	}
+	return ret, nil
+}
+
+func (p *PprofServer) ListInstances(ctx context.Context, req *db.ListInstancesRequest) (*db.ListInstancesResponse, error) {
+	instances, err := p.store.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &db.ListInstancesResponse{
+		Instances: make([]*db.Instance, 0, len(instances)),
+	}
+	for _, inst := range instances {
+		resp.Instances = append(resp.Instances, &db.Instance{
+			InstanceId: inst.Id,
+			Labels:     inst.Labels,
+			FirstSeen:  timestamppb.New(inst.FirstSeen),
+			LastSeen:   timestamppb.New(inst.LastSeen),
+		})
+	}
+	return resp, nil
+}
+
+func (p *PprofServer) ListProfileTypes(ctx context.Context, req *db.ListProfileTypesRequest) (*db.ListProfileTypesResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	types, err := p.store.ListProfileTypes(ctx, req.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	resp := &db.ListProfileTypesResponse{
+		Types: make([]*db.ProfileType, 0, len(types)),
+	}
+	for _, t := range types {
+		resp.Types = append(resp.Types, &db.ProfileType{
+			Type:      t.Type,
+			FirstSeen: timestamppb.New(t.FirstSeen),
+			LastSeen:  timestamppb.New(t.LastSeen),
+			Count:     int64(t.Count),
+		})
+	}
+	return resp, nil
 }
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDiffProfile(t *testing.T) {
+	now := time.Now()
+	heap := heapProfile(now.Add(-time.Hour), map[string]int64{"main.a": 1}, nil)
+	heap.PeriodType = &profile.ValueType{Type: "space", Unit: "bytes"}
+	testCases := []struct {
+		name string
+		base *profile.Profile
+		// values of the diffed profile, by function and whether the sample comes from the base
+		expected map[string]map[bool]int64
+		code     codes.Code
+	}{
+		{
+			name: "subtracts the base",
+			base: cpuProfile(now.Add(-time.Hour), map[string]int64{"main.work": 1, "main.wait": 3}),
+			expected: map[string]map[bool]int64{
+				"main.work": {false: 4, true: -1},
+				"main.wait": {false: 1, true: -3},
+				"main.gc":   {false: 2},
+			},
+		},
+		{
+			name: "empty base",
+			base: cpuProfile(now.Add(-time.Hour), nil),
+			expected: map[string]map[bool]int64{
+				"main.work": {false: 4},
+				"main.wait": {false: 1},
+				"main.gc":   {false: 2},
+			},
+		},
+		{
+			name: "incompatible base",
+			base: heap,
+			code: codes.FailedPrecondition,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prof := cpuProfile(now, map[string]int64{"main.work": 4, "main.wait": 1, "main.gc": 2})
+			ret, err := diffProfile(prof, tc.base)
+			if status.Code(err) != tc.code {
+				t.Fatalf("expected %s, got %v", tc.code, err)
+			}
+			if err != nil {
+				return
+			}
+			got := map[string]map[bool]int64{}
+			for _, s := range ret.Sample {
+				name := s.Location[0].Line[0].Function.Name
+				if got[name] == nil {
+					got[name] = map[bool]int64{}
+				}
+				// tagged like `pprof -diff_base`, so the pprof UI tells base samples apart
+				isBase := reflect.DeepEqual(s.Label["pprof::base"], []string{"true"})
+				got[name][isBase] += s.Value[0]
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
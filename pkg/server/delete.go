@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (p *PprofServer) Delete(ctx context.Context, req *db.DeleteRequest) (*db.DeleteResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	var sel storage.Selector
+	if req.Selector != "" {
+		s, err := storage.ParseSelector(req.Selector)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		sel = s
+	} else {
+		m, err := storage.NewMatcher(storage.InstanceLabel, storage.MatchEqual, req.InstanceId)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		sel = storage.Selector{m}
+	}
+	// unlike queries, unset timestamps don't stop at the current time so profiles with skewed clocks are deleted too
+	start, end := time.Unix(0, 0), time.Unix(0, math.MaxInt64)
+	if req.Start != nil {
+		start = req.Start.AsTime()
+	}
+	if req.End != nil {
+		end = req.End.AsTime()
+	}
+	deleted, err := p.store.Delete(ctx, sel, req.Type, start, end)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("deleted %d profiles matching %s", deleted, sel)
+	return &db.DeleteResponse{
+		Deleted: int64(deleted),
+	}, nil
+}
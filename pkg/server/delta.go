@@ -0,0 +1,231 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/sirupsen/logrus"
+)
+
+// cumulativeSampleTypes are the sample types the go runtime reports as totals since process start.
+//
+// Goroutine profiles are snapshots of the goroutines alive at collection time, and the inuse_*
+// sample types of heap profiles are point in time values, so they are stored as is.
+var cumulativeSampleTypes = map[string]struct{}{
+	// heap & allocs
+	"alloc_objects": {},
+	"alloc_space":   {},
+	// mutex & block
+	"contentions": {},
+	"delay":       {},
+}
+
+// profiles collected over a window, for example with `/debug/pprof/mutex?seconds=30`,
+// are already deltas computed by the target
+const minCumulativeWindow = time.Second
+
+// deltaTracker converts cumulative profiles into per-interval deltas against the
+// previous profile of the same instance/type
+type deltaTracker struct {
+	mu sync.Mutex
+	// id -> profile type -> last cumulative profile
+	last map[string]map[string]*profile.Profile
+}
+
+func newDeltaTracker() *deltaTracker {
+	return &deltaTracker{
+		last: map[string]map[string]*profile.Profile{},
+	}
+}
+
+func cumulativeIndices(prof *profile.Profile) []int {
+	if prof.DurationNanos >= minCumulativeWindow.Nanoseconds() {
+		return nil
+	}
+	ret := []int{}
+	for i, st := range prof.SampleType {
+		if _, ok := cumulativeSampleTypes[st.Type]; ok {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+// delta returns the profile that should be stored for an incoming profile, whether its cumulative
+// values only serve as the baseline of the next delta, and a commit func to call once it was stored,
+// which makes the incoming profile the baseline of the next delta. Uncommitted profiles, e.g. rejected by an unavailable store, are diffed against the same baseline when retried.
+func (d *deltaTracker) delta(instanceId, profileType string, prof *profile.Profile) (*profile.Profile, bool, func()) {
+	indices := cumulativeIndices(prof)
+	if len(indices) == 0 {
+		return prof, false, func() {}
+	}
+	d.mu.Lock()
+	prev := d.last[instanceId][profileType]
+	d.mu.Unlock()
+	if prev != nil && prof.TimeNanos < prev.TimeNanos {
+		logrus.Warnf("received out of order %s profile for %s, storing it as is", profileType, instanceId)
+		return prof, false, func() {}
+	}
+	commit := func() {
+		d.commit(instanceId, profileType, prof)
+	}
+
+	if prev == nil {
+		// the first profile we've seen holds everything since process start, or since before the
+		// server restarted, so its cumulative values only serve as the baseline of the next delta
+		return baseline(prof, indices), true, commit
+	}
+	if restarted(prev, prof, indices) {
+		// the profile holds everything since the process restarted, after the previous profile
+		logrus.Infof("%s totals for %s went backwards, the process restarted", profileType, instanceId)
+		return prof, false, commit
+	}
+	ret, err := computeDelta(prev, prof, indices)
+	if err != nil {
+		logrus.Infof("resetting %s delta for %s : %s", profileType, instanceId, err)
+		return baseline(prof, indices), true, commit
+	}
+	return ret, false, commit
+}
+
+// commit makes prof the baseline of the next delta, unless a more recent profile was committed concurrently
+func (d *deltaTracker) commit(instanceId, profileType string, prof *profile.Profile) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.last[instanceId]; !ok {
+		d.last[instanceId] = map[string]*profile.Profile{}
+	}
+	if prev := d.last[instanceId][profileType]; prev == nil || prev.TimeNanos <= prof.TimeNanos {
+		d.last[instanceId][profileType] = prof
+	}
+}
+
+// baseline zeroes the cumulative values of the profile, keeping its point in time values such as inuse_*.
+// Returns nil when no values are left to store.
+func baseline(prof *profile.Profile, indices []int) *profile.Profile {
+	// compacting returns a copy we can modify
+	ret := prof.Compact()
+	samples := make([]*profile.Sample, 0, len(ret.Sample))
+	for _, s := range ret.Sample {
+		for _, i := range indices {
+			s.Value[i] = 0
+		}
+		if !isZero(s) {
+			samples = append(samples, s)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	ret.Sample = samples
+	return ret.Compact()
+}
+
+// restarted reports whether the total of a cumulative sample type went backwards.
+// Totals are compared since the per stack values of sampled profiles, e.g. heap alloc_*,
+// are scaled estimates that can shrink between profiles.
+func restarted(prev, prof *profile.Profile, indices []int) bool {
+	if len(prev.SampleType) != len(prof.SampleType) {
+		return false
+	}
+	prevTotals, totals := sampleTotals(prev, indices), sampleTotals(prof, indices)
+	for _, i := range indices {
+		if totals[i] < prevTotals[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func sampleTotals(prof *profile.Profile, indices []int) []int64 {
+	ret := make([]int64, len(prof.SampleType))
+	for _, s := range prof.Sample {
+		for _, i := range indices {
+			ret[i] += s.Value[i]
+		}
+	}
+	return ret
+}
+
+// computeDelta subtracts the cumulative values of prev from prof.
+// Values of a stack that went backwards are clamped to 0.
+func computeDelta(prev, prof *profile.Profile, indices []int) (*profile.Profile, error) {
+	if len(prev.SampleType) != len(prof.SampleType) {
+		return nil, fmt.Errorf("sample types changed")
+	}
+	for i := range prof.SampleType {
+		if prev.SampleType[i].Type != prof.SampleType[i].Type {
+			return nil, fmt.Errorf("sample types changed")
+		}
+	}
+
+	prevValues := map[string][]int64{}
+	for _, s := range prev.Sample {
+		key := sampleKey(s)
+		if _, ok := prevValues[key]; !ok {
+			prevValues[key] = make([]int64, len(s.Value))
+		}
+		for _, i := range indices {
+			prevValues[key][i] += s.Value[i]
+		}
+	}
+
+	// compacting merges samples with identical stacks & labels, and returns a copy we can modify
+	ret := prof.Compact()
+	samples := make([]*profile.Sample, 0, len(ret.Sample))
+	for _, s := range ret.Sample {
+		if prevV, ok := prevValues[sampleKey(s)]; ok {
+			for _, i := range indices {
+				s.Value[i] = max(s.Value[i]-prevV[i], 0)
+			}
+		}
+		if isZero(s) {
+			continue
+		}
+		samples = append(samples, s)
+	}
+	ret.Sample = samples
+	if prof.TimeNanos > prev.TimeNanos {
+		ret.DurationNanos = prof.TimeNanos - prev.TimeNanos
+		ret.TimeNanos = prev.TimeNanos
+	}
+	return ret.Compact(), nil
+}
+
+func isZero(s *profile.Sample) bool {
+	for _, v := range s.Value {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sampleKey identifies a sample's stack and labels independently of profile specific ids
+func sampleKey(s *profile.Sample) string {
+	b := strings.Builder{}
+	for _, loc := range s.Location {
+		fmt.Fprintf(&b, "%x", loc.Address)
+		for _, line := range loc.Line {
+			if line.Function != nil {
+				b.WriteString(line.Function.Name)
+			}
+			fmt.Fprintf(&b, ":%d", line.Line)
+		}
+		b.WriteByte('|')
+	}
+	keys := make([]string, 0, len(s.Label)+len(s.NumLabel))
+	for k, v := range s.Label {
+		keys = append(keys, fmt.Sprintf("%s=%v", k, v))
+	}
+	for k, v := range s.NumLabel {
+		keys = append(keys, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(keys)
+	b.WriteString(strings.Join(keys, ","))
+	return b.String()
+}
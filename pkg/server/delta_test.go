@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// heapProfile builds a heap profile with alloc_space & inuse_space values for each function
+func heapProfile(at time.Time, alloc, inuse map[string]int64) *profile.Profile {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "alloc_space", Unit: "bytes"},
+			{Type: "inuse_space", Unit: "bytes"},
+		},
+		TimeNanos: at.UnixNano(),
+	}
+	for _, name := range []string{"main.a", "main.b", "main.c"} {
+		if _, ok := alloc[name]; !ok {
+			if _, ok := inuse[name]; !ok {
+				continue
+			}
+		}
+		fn := &profile.Function{ID: uint64(len(prof.Function) + 1), Name: name}
+		loc := &profile.Location{ID: fn.ID, Line: []profile.Line{{Function: fn}}}
+		prof.Function = append(prof.Function, fn)
+		prof.Location = append(prof.Location, loc)
+		prof.Sample = append(prof.Sample, &profile.Sample{
+			Location: []*profile.Location{loc},
+			Value:    []int64{alloc[name], inuse[name]},
+		})
+	}
+	return prof
+}
+
+// values returns the function -> values of a profile
+func values(prof *profile.Profile) map[string][]int64 {
+	ret := map[string][]int64{}
+	for _, s := range prof.Sample {
+		name := s.Location[0].Line[0].Function.Name
+		if _, ok := ret[name]; !ok {
+			ret[name] = make([]int64, len(s.Value))
+		}
+		for i, v := range s.Value {
+			ret[name][i] += v
+		}
+	}
+	return ret
+}
+
+func equalValues(a, b map[string][]int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, va := range a {
+		vb, ok := b[k]
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if va[i] != vb[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestComputeDelta(t *testing.T) {
+	start := time.Unix(1000, 0)
+	prev := heapProfile(start, map[string]int64{"main.a": 100, "main.b": 50}, map[string]int64{"main.a": 10, "main.b": 5})
+	testCases := []struct {
+		name     string
+		prof     *profile.Profile
+		expected map[string][]int64
+	}{
+		{
+			name:     "growing counters",
+			prof:     heapProfile(start.Add(time.Minute), map[string]int64{"main.a": 150, "main.b": 50}, map[string]int64{"main.a": 20, "main.b": 5}),
+			expected: map[string][]int64{"main.a": {50, 20}, "main.b": {0, 5}},
+		},
+		{
+			name:     "new stack",
+			prof:     heapProfile(start.Add(time.Minute), map[string]int64{"main.a": 100, "main.b": 50, "main.c": 30}, nil),
+			expected: map[string][]int64{"main.c": {30, 0}},
+		},
+		{
+			// sampled heap values are scaled estimates that can shrink between profiles
+			name:     "shrinking estimate is clamped",
+			prof:     heapProfile(start.Add(time.Minute), map[string]int64{"main.a": 90, "main.b": 80}, nil),
+			expected: map[string][]int64{"main.b": {30, 0}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ret, err := computeDelta(prev, tc.prof, []int{0})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := values(ret); !equalValues(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+			if ret.TimeNanos != prev.TimeNanos || ret.DurationNanos != time.Minute.Nanoseconds() {
+				t.Errorf("expected the delta to cover the interval between profiles, got %d+%d", ret.TimeNanos, ret.DurationNanos)
+			}
+		})
+	}
+
+	changed := heapProfile(start.Add(time.Minute), map[string]int64{"main.a": 150}, nil)
+	changed.SampleType[0].Type = "alloc_objects"
+	if _, err := computeDelta(prev, changed, []int{0}); err == nil {
+		t.Error("expected an error when sample types change")
+	}
+}
+
+// committed commits a delta, as if the store accepted it
+func committed(prof *profile.Profile, _ bool, commit func()) *profile.Profile {
+	commit()
+	return prof
+}
+
+func TestDeltaTracker(t *testing.T) {
+	start := time.Unix(1000, 0)
+	d := newDeltaTracker()
+
+	// the first profile only keeps point in time values
+	first := committed(d.delta("a", "heap", heapProfile(start, map[string]int64{"main.a": 100}, map[string]int64{"main.a": 10})))
+	if first == nil {
+		t.Fatal("expected inuse values of the first profile to be stored")
+	}
+	if got, expected := values(first), map[string][]int64{"main.a": {0, 10}}; !equalValues(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	second := committed(d.delta("a", "heap", heapProfile(start.Add(time.Minute), map[string]int64{"main.a": 160}, map[string]int64{"main.a": 12})))
+	if got, expected := values(second), map[string][]int64{"main.a": {60, 12}}; !equalValues(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// totals went backwards, the process restarted and the profile holds everything since then
+	restart := heapProfile(start.Add(2*time.Minute), map[string]int64{"main.a": 20}, map[string]int64{"main.a": 3})
+	third := committed(d.delta("a", "heap", restart))
+	if got, expected := values(third), map[string][]int64{"main.a": {20, 3}}; !equalValues(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// profiles holding only cumulative values have nothing to store before the baseline
+	mutex := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "contentions", Unit: "count"}, {Type: "delay", Unit: "nanoseconds"}},
+		TimeNanos:  start.UnixNano(),
+	}
+	fn := &profile.Function{ID: 1, Name: "main.lock"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn}}}
+	mutex.Function, mutex.Location = []*profile.Function{fn}, []*profile.Location{loc}
+	mutex.Sample = []*profile.Sample{{Location: []*profile.Location{loc}, Value: []int64{5, 500}}}
+	if ret := committed(d.delta("a", "mutex", mutex)); ret != nil {
+		t.Errorf("expected the mutex baseline not to be stored, got %v", values(ret))
+	}
+
+	// profiles collected over a window are already deltas
+	windowed := heapProfile(start, map[string]int64{"main.a": 100}, nil)
+	windowed.DurationNanos = (30 * time.Second).Nanoseconds()
+	if ret := committed(d.delta("b", "heap", windowed)); ret != windowed {
+		t.Error("expected windowed profiles to be stored as is")
+	}
+}
+
+func TestIngestRetriesDeltaAfterUnavailable(t *testing.T) {
+	ctx := context.Background()
+	start := time.Unix(1000, 0)
+	store := &unavailableStore{
+		ProfileStore: mem.NewProfileMemStorage(),
+		healthy:      1,
+	}
+	p := NewPprofServer(store)
+	if err := p.Ingest(ctx, "a", "heap", map[string]string{}, heapProfile(start, map[string]int64{"main.a": 100}, map[string]int64{"main.a": 10})); err != nil {
+		t.Fatal(err)
+	}
+
+	next := heapProfile(start.Add(time.Minute), map[string]int64{"main.a": 160}, map[string]int64{"main.a": 12})
+	if err := p.Ingest(ctx, "a", "heap", map[string]string{}, next); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the store to be unavailable, got %v", err)
+	}
+	// the collector retries the same profile once the store is back
+	store.healthy = 1
+	if err := p.Ingest(ctx, "a", "heap", map[string]string{}, next); err != nil {
+		t.Fatal(err)
+	}
+
+	profs, err := store.Range(ctx, "a", "heap", time.Unix(0, 0), start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profs) != 2 {
+		t.Fatalf("expected 2 stored profiles, got %d", len(profs))
+	}
+	found := false
+	for _, prof := range profs {
+		if equalValues(values(prof), map[string][]int64{"main.a": {60, 12}}) {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected the retried profile to be stored as the delta since the first profile")
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// encodeProfile serializes a merged profile in the requested format
+func encodeProfile(prof *profile.Profile, format db.Format, sampleType string) ([]byte, error) {
+	if format == db.Format_FORMAT_PPROF {
+		b := bytes.NewBuffer([]byte{})
+		if err := prof.Write(b); err != nil { // note: this is compressed by default
+			return nil, status.Errorf(codes.Internal, "failed to write profile: %s to buffer", err)
+		}
+		return b.Bytes(), nil
+	}
+
+	idx, err := sampleIndex(prof, sampleType)
+	if err != nil {
+		return nil, err
+	}
+	var ret []byte
+	switch format {
+	case db.Format_FORMAT_FLAMEGRAPH:
+		ret, err = json.Marshal(flamegraph(prof, idx))
+	case db.Format_FORMAT_SPEEDSCOPE:
+		ret, err = json.Marshal(speedscope(prof, idx))
+	case db.Format_FORMAT_FOLDED:
+		ret = folded(prof, idx)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown format %s", format)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode profile as %s: %s", format, err)
+	}
+	return ret, nil
+}
+
+// sampleIndex returns the index of the sample type, defaulting to the last one like pprof does
+func sampleIndex(prof *profile.Profile, sampleType string) (int, error) {
+	if len(prof.SampleType) == 0 {
+		return 0, status.Error(codes.FailedPrecondition, "profile has no sample types")
+	}
+	if sampleType == "" {
+		sampleType = prof.DefaultSampleType
+	}
+	if sampleType == "" {
+		return len(prof.SampleType) - 1, nil
+	}
+	for i, st := range prof.SampleType {
+		if st.Type == sampleType {
+			return i, nil
+		}
+	}
+	types := make([]string, 0, len(prof.SampleType))
+	for _, st := range prof.SampleType {
+		types = append(types, st.Type)
+	}
+	return 0, status.Errorf(codes.InvalidArgument, "unknown sample type %s, expected one of %s", sampleType, strings.Join(types, ", "))
+}
+
+type frame struct {
+	Name string
+	File string
+}
+
+// stack returns the frames of a sample from the root to the leaf, expanding inlined functions
+func stack(s *profile.Sample) []frame {
+	ret := []frame{}
+	for i := len(s.Location) - 1; i >= 0; i-- {
+		loc := s.Location[i]
+		if len(loc.Line) == 0 {
+			ret = append(ret, frame{Name: fmt.Sprintf("0x%x", loc.Address)})
+			continue
+		}
+		// the last line is the caller of the previous ones
+		for j := len(loc.Line) - 1; j >= 0; j-- {
+			f := frame{Name: "?"}
+			if fn := loc.Line[j].Function; fn != nil {
+				f = frame{Name: fn.Name, File: fn.Filename}
+			}
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// `;` separates frames and spaces separate the value of folded stacks
+var foldedReplacer = strings.NewReplacer(";", ":", " ", "_")
+
+// folded renders the profile as collapsed stacks, sorted for stable output
+func folded(prof *profile.Profile, idx int) []byte {
+	values := map[string]int64{}
+	for _, s := range prof.Sample {
+		if s.Value[idx] == 0 {
+			continue
+		}
+		frames := stack(s)
+		names := make([]string, 0, len(frames))
+		for _, f := range frames {
+			names = append(names, foldedReplacer.Replace(f.Name))
+		}
+		values[strings.Join(names, ";")] += s.Value[idx]
+	}
+	stacks := make([]string, 0, len(values))
+	for k := range values {
+		stacks = append(stacks, k)
+	}
+	sort.Strings(stacks)
+	b := &bytes.Buffer{}
+	for _, k := range stacks {
+		fmt.Fprintf(b, "%s %d\n", k, values[k])
+	}
+	return b.Bytes()
+}
+
+type flamegraphNode struct {
+	Name     string            `json:"name"`
+	Value    int64             `json:"value"`
+	Children []*flamegraphNode `json:"children"`
+
+	children map[string]*flamegraphNode
+}
+
+func (n *flamegraphNode) sortChildren() {
+	n.Children = make([]*flamegraphNode, 0, len(n.children))
+	for _, c := range n.children {
+		c.sortChildren()
+		n.Children = append(n.Children, c)
+	}
+	sort.Slice(n.Children, func(i, j int) bool {
+		return n.Children[i].Name < n.Children[j].Name
+	})
+}
+
+// flamegraph builds the d3-flame-graph tree, where each node's value includes its children
+func flamegraph(prof *profile.Profile, idx int) *flamegraphNode {
+	root := &flamegraphNode{
+		Name:     "root",
+		children: map[string]*flamegraphNode{},
+	}
+	for _, s := range prof.Sample {
+		v := s.Value[idx]
+		if v == 0 {
+			continue
+		}
+		root.Value += v
+		node := root
+		for _, f := range stack(s) {
+			child, ok := node.children[f.Name]
+			if !ok {
+				child = &flamegraphNode{
+					Name:     f.Name,
+					children: map[string]*flamegraphNode{},
+				}
+				node.children[f.Name] = child
+			}
+			child.Value += v
+			node = child
+		}
+	}
+	root.sortChildren()
+	return root
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+	File string `json:"file,omitempty"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int64   `json:"startValue"`
+	EndValue   int64   `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int64 `json:"weights"`
+}
+
+type speedscopeFile struct {
+	Schema string `json:"$schema"`
+	Shared struct {
+		Frames []speedscopeFrame `json:"frames"`
+	} `json:"shared"`
+	Profiles []speedscopeProfile `json:"profiles"`
+	Name     string              `json:"name"`
+	Exporter string              `json:"exporter"`
+}
+
+// speedscopeUnit maps pprof units to the ones speedscope understands
+func speedscopeUnit(unit string) string {
+	switch unit {
+	case "nanoseconds", "microseconds", "milliseconds", "seconds", "bytes":
+		return unit
+	default:
+		return "none"
+	}
+}
+
+// speedscope exports the profile as a single sampled speedscope profile
+func speedscope(prof *profile.Profile, idx int) *speedscopeFile {
+	st := prof.SampleType[idx]
+	ret := &speedscopeFile{
+		Schema:   "https://www.speedscope.app/file-format-schema.json",
+		Name:     st.Type,
+		Exporter: "pprof-server",
+	}
+	sp := speedscopeProfile{
+		Type:    "sampled",
+		Name:    st.Type,
+		Unit:    speedscopeUnit(st.Unit),
+		Samples: [][]int{},
+		Weights: []int64{},
+	}
+	frames := map[frame]int{}
+	ret.Shared.Frames = []speedscopeFrame{}
+	for _, s := range prof.Sample {
+		v := s.Value[idx]
+		if v == 0 {
+			continue
+		}
+		stackFrames := stack(s)
+		indices := make([]int, 0, len(stackFrames))
+		for _, f := range stackFrames {
+			i, ok := frames[f]
+			if !ok {
+				i = len(ret.Shared.Frames)
+				frames[f] = i
+				ret.Shared.Frames = append(ret.Shared.Frames, speedscopeFrame{Name: f.Name, File: f.File})
+			}
+			indices = append(indices, i)
+		}
+		sp.Samples = append(sp.Samples, indices)
+		sp.Weights = append(sp.Weights, v)
+		sp.EndValue += v
+	}
+	ret.Profiles = []speedscopeProfile{sp}
+	return ret
+}
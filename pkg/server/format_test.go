@@ -0,0 +1,179 @@
+package server
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stackedProfile calls encoding/json.Marshal inlined in main.work, an unsymbolized address
+// and a function whose name holds folded stack separators from main.main
+func stackedProfile() *profile.Profile {
+	fns := []*profile.Function{
+		{ID: 1, Name: "main.main", Filename: "main.go"},
+		{ID: 2, Name: "main.work", Filename: "main.go"},
+		{ID: 3, Name: "encoding/json.Marshal", Filename: "encode.go"},
+		{ID: 4, Name: "pkg.(*T).a;b c", Filename: "t.go"},
+	}
+	main := &profile.Location{ID: 1, Line: []profile.Line{{Function: fns[0]}}}
+	// the first line is the inlined callee
+	inlined := &profile.Location{ID: 2, Line: []profile.Line{{Function: fns[2]}, {Function: fns[1]}}}
+	addr := &profile.Location{ID: 3, Address: 0x1234}
+	odd := &profile.Location{ID: 4, Line: []profile.Line{{Function: fns[3]}}}
+	return &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		Function: fns,
+		Location: []*profile.Location{main, inlined, addr, odd},
+		Sample: []*profile.Sample{
+			{Location: []*profile.Location{inlined, main}, Value: []int64{1, 10}},
+			{Location: []*profile.Location{addr, main}, Value: []int64{2, 20}},
+			{Location: []*profile.Location{inlined, main}, Value: []int64{3, 30}},
+			{Location: []*profile.Location{odd, main}, Value: []int64{4, 0}},
+		},
+	}
+}
+
+func TestFolded(t *testing.T) {
+	testCases := []struct {
+		name     string
+		idx      int
+		expected string
+	}{
+		{
+			name: "escapes separators",
+			idx:  0,
+			expected: "main.main;0x1234 2\n" +
+				"main.main;main.work;encoding/json.Marshal 4\n" +
+				"main.main;pkg.(*T).a:b_c 4\n",
+		},
+		{
+			name: "skips zero values",
+			idx:  1,
+			expected: "main.main;0x1234 20\n" +
+				"main.main;main.work;encoding/json.Marshal 40\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(folded(stackedProfile(), tc.idx)); got != tc.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFlamegraph(t *testing.T) {
+	got, err := json.Marshal(flamegraph(stackedProfile(), 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"name":"root","value":60,"children":[` +
+		`{"name":"main.main","value":60,"children":[` +
+		`{"name":"0x1234","value":20,"children":[]},` +
+		`{"name":"main.work","value":40,"children":[` +
+		`{"name":"encoding/json.Marshal","value":40,"children":[]}]}]}]}`
+	if string(got) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestSpeedscope(t *testing.T) {
+	testCases := []struct {
+		name     string
+		idx      int
+		unit     string
+		frames   []speedscopeFrame
+		samples  [][]int
+		weights  []int64
+		endValue int64
+	}{
+		{
+			name: "unsupported units",
+			idx:  0,
+			unit: "none",
+			frames: []speedscopeFrame{
+				{Name: "main.main", File: "main.go"},
+				{Name: "main.work", File: "main.go"},
+				{Name: "encoding/json.Marshal", File: "encode.go"},
+				{Name: "0x1234"},
+				{Name: "pkg.(*T).a;b c", File: "t.go"},
+			},
+			samples:  [][]int{{0, 1, 2}, {0, 3}, {0, 1, 2}, {0, 4}},
+			weights:  []int64{1, 2, 3, 4},
+			endValue: 10,
+		},
+		{
+			name: "skips zero values",
+			idx:  1,
+			unit: "nanoseconds",
+			frames: []speedscopeFrame{
+				{Name: "main.main", File: "main.go"},
+				{Name: "main.work", File: "main.go"},
+				{Name: "encoding/json.Marshal", File: "encode.go"},
+				{Name: "0x1234"},
+			},
+			samples:  [][]int{{0, 1, 2}, {0, 3}, {0, 1, 2}},
+			weights:  []int64{10, 20, 30},
+			endValue: 60,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := speedscope(stackedProfile(), tc.idx)
+			if !reflect.DeepEqual(got.Shared.Frames, tc.frames) {
+				t.Errorf("expected frames %v, got %v", tc.frames, got.Shared.Frames)
+			}
+			if len(got.Profiles) != 1 {
+				t.Fatalf("expected a single profile, got %d", len(got.Profiles))
+			}
+			sp := got.Profiles[0]
+			if sp.Unit != tc.unit {
+				t.Errorf("expected unit %s, got %s", tc.unit, sp.Unit)
+			}
+			if !reflect.DeepEqual(sp.Samples, tc.samples) {
+				t.Errorf("expected samples %v, got %v", tc.samples, sp.Samples)
+			}
+			if !reflect.DeepEqual(sp.Weights, tc.weights) {
+				t.Errorf("expected weights %v, got %v", tc.weights, sp.Weights)
+			}
+			if sp.StartValue != 0 || sp.EndValue != tc.endValue {
+				t.Errorf("expected values [0, %d], got [%d, %d]", tc.endValue, sp.StartValue, sp.EndValue)
+			}
+		})
+	}
+}
+
+func TestSampleIndex(t *testing.T) {
+	testCases := []struct {
+		name           string
+		sampleType     string
+		profileDefault string
+		expected       int
+		code           codes.Code
+	}{
+		{name: "defaults to the last sample type", expected: 1},
+		{name: "profile default", profileDefault: "samples", expected: 0},
+		{name: "requested sample type", sampleType: "samples", profileDefault: "cpu", expected: 0},
+		{name: "unknown sample type", sampleType: "alloc_space", code: codes.InvalidArgument},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prof := stackedProfile()
+			prof.DefaultSampleType = tc.profileDefault
+			idx, err := sampleIndex(prof, tc.sampleType)
+			if status.Code(err) != tc.code {
+				t.Fatalf("expected %s, got %v", tc.code, err)
+			}
+			if err == nil && idx != tc.expected {
+				t.Errorf("expected sample index %d, got %d", tc.expected, idx)
+			}
+		})
+	}
+}
@@ -3,32 +3,62 @@ package server
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
 	"github.com/google/pprof/profile"
 	"github.com/google/pprof/public/ui"
 	"github.com/sirupsen/logrus"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type PprofHttpServer struct {
 	dbClient   db.DBClient
+	logsClient collogspb.LogsServiceClient
 	listenAddr string
 	mux        *http.ServeMux
 	httpServer *http.Server
+	handlers   *handlerCache
+}
+
+type HttpOption func(*PprofHttpServer)
+
+// WithHandlerCache caches up to size web UI handlers for ttl, a size <= 0 disables the cache
+func WithHandlerCache(size int, ttl time.Duration) HttpOption {
+	return func(p *PprofHttpServer) {
+		p.handlers = newHandlerCache(size, ttl)
+	}
 }
 
 func NewHttpServer(
 	listenAddr string,
 	dbClient db.DBClient,
+	logsClient collogspb.LogsServiceClient,
+	opts ...HttpOption,
 ) *PprofHttpServer {
-	return &PprofHttpServer{
+	p := &PprofHttpServer{
 		mux:        http.DefaultServeMux,
 		listenAddr: listenAddr,
 		dbClient:   dbClient,
+		logsClient: logsClient,
+		handlers:   newHandlerCache(32, 5*time.Minute),
 	}
+	p.httpServer = &http.Server{
+		Handler: p.mux,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *PprofHttpServer) ListenAndServe() error {
@@ -36,12 +66,9 @@ func (p *PprofHttpServer) ListenAndServe() error {
 	if err != nil {
 		return err
 	}
-	server := &http.Server{
-		Handler: p.mux,
-	}
 	p.registerHandlers()
 
-	return server.Serve(listener)
+	return p.httpServer.Serve(listener)
 }
 
 func (p *PprofHttpServer) Shutdown(ctx context.Context) error {
@@ -49,39 +76,229 @@ func (p *PprofHttpServer) Shutdown(ctx context.Context) error {
 }
 
 func (p *PprofHttpServer) registerHandlers() {
+	p.mux.HandleFunc("/", p.index)
 	p.mux.HandleFunc("/ui/", p.displayProfile)
+	p.mux.HandleFunc("/v1/logs", p.exportLogs)
+	p.mux.HandleFunc("/ingest", p.ingestProfile)
+	p.mux.HandleFunc("/api/profile", p.downloadProfile)
+	p.mux.HandleFunc("/api/top", p.top)
+	p.mux.HandleFunc("/api/function-series", p.functionSeries)
+	p.mux.HandleFunc("/api/pgo", p.pgo)
+	p.registerPyroscopeHandlers()
 }
 
 func (p *PprofHttpServer) displayProfile(w http.ResponseWriter, r *http.Request) {
-	pathParts := strings.SplitN(r.URL.Path[len("/ui/"):], "/", 3)
-	if len(pathParts) < 1 {
-		http.Error(w, "id not provided", http.StatusBadRequest)
+	// instance ids may contain slashes, escaped as %2F by the index's links
+	pathParts := strings.SplitN(strings.TrimPrefix(r.URL.EscapedPath(), "/ui/"), "/", 3)
+	if len(pathParts) < 2 {
+		http.Error(w, "id and profile type not provided", http.StatusBadRequest)
+		return
+	}
+	id, err := url.PathUnescape(pathParts[0])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	pType, err := url.PathUnescape(pathParts[1])
+	if err != nil {
+		http.Error(w, "invalid profile type", http.StatusBadRequest)
+		return
+	}
+	// the web UI is served relative to /ui/<id>/<profile_type>
+	view := ""
+	if len(pathParts) == 3 {
+		view = "/" + pathParts[2]
+	}
+	uiReq, err := stripUIPrefix(r, view)
+	if err != nil {
+		http.Error(w, "invalid path", http.StatusBadRequest)
 		return
 	}
 
-	id := pathParts[0]
-	pType := pathParts[1]
+	q := r.URL.Query()
+	now := time.Now()
+	key := handlerCacheKey{
+		instanceId:  id,
+		profileType: pType,
+		from:        q.Get("from"),
+		to:          q.Get("to"),
+		base: url.Values{
+			"base_instance": q["base_instance"],
+			"base_selector": q["base_selector"],
+			"base_from":     q["base_from"],
+			"base_to":       q["base_to"],
+		}.Encode(),
+	}
+	var version profileVersion
+	cacheable := false
+	if p.handlers.enabled() {
+		v, err := p.profileVersion(r.Context(), id, pType)
+		switch {
+		case err == nil:
+			if handler, ok := p.handlers.get(key, v, now); ok {
+				handler.ServeHTTP(w, uiReq)
+				return
+			}
+			version, cacheable = v, true
+		case status.Code(err) != codes.NotFound:
+			logrus.WithError(err).Warn("failed to check profile version, skipping cache")
+		}
+	}
 
-	data, err := p.dbClient.Get(r.Context(), &db.GetProfileRequest{
+	start, end, err := timeRangeFromQuery(q, "from", "to", now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	base, err := baseFromQuery(q, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := p.getProfile(r.Context(), &db.GetProfileRequest{
 		InstanceId: id,
 		Type:       pType,
+		Start:      start,
+		End:        end,
+		Base:       base,
 	})
 	if err != nil {
 		logrus.WithError(err).Error("failed to get profile")
-		http.Error(w, "failed to get profile", http.StatusInternalServerError)
+		writeGRPCError(w, "failed to get profile", err)
 		return
 	}
 
-	prof, err := profile.ParseData(data.Data)
+	prof, err := profile.ParseData(data)
 	if err != nil {
 		logrus.WithError(err).Error("failed to parse profile")
 		http.Error(w, "failed to parse profile", http.StatusInternalServerError)
 		return
 	}
 
-	handler := http.StripPrefix(fmt.Sprintf("/ui/%s/%s", id, pType), createHandlerFromProfile(prof))
+	handler := createHandlerFromProfile(prof)
+	if cacheable {
+		p.handlers.add(key, version, handler, now)
+	}
 	// Serve the request using the new handler
-	handler.ServeHTTP(w, r)
+	handler.ServeHTTP(w, uiReq)
+}
+
+// stripUIPrefix works like http.StripPrefix, leaving the escaped view path after /ui/<id>/<profile_type>.
+// http.StripPrefix can't strip ids holding slashes, since they are escaped in the raw path only.
+func stripUIPrefix(r *http.Request, view string) (*http.Request, error) {
+	path, err := url.PathUnescape(view)
+	if err != nil {
+		return nil, err
+	}
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = path
+	r2.URL.RawPath = view
+	return r2, nil
+}
+
+// getProfile reassembles the chunks streamed by GetStream, merged profiles can exceed the gRPC message size limit
+func (p *PprofHttpServer) getProfile(ctx context.Context, req *db.GetProfileRequest) ([]byte, error) {
+	stream, err := p.dbClient.GetStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, chunk.Data...)
+	}
+}
+
+// profileVersion identifies the profiles currently stored for an instance's profile type
+func (p *PprofHttpServer) profileVersion(ctx context.Context, instanceId, profileType string) (profileVersion, error) {
+	resp, err := p.dbClient.ListProfileTypes(ctx, &db.ListProfileTypesRequest{
+		InstanceId: instanceId,
+	})
+	if err != nil {
+		return profileVersion{}, err
+	}
+	for _, t := range resp.GetTypes() {
+		if t.Type == profileType {
+			return profileVersion{
+				count:    t.Count,
+				lastSeen: t.LastSeen.AsTime(),
+			}, nil
+		}
+	}
+	return profileVersion{}, nil
+}
+
+// parseTime parses RFC3339 timestamps, unix timestamps in seconds, or times relative to now such as `now-15m`
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "now" {
+		return now, nil
+	}
+	if rel, ok := strings.CutPrefix(value, "now-"); ok {
+		d, err := time.ParseDuration(rel)
+		if err != nil || d < 0 {
+			return time.Time{}, fmt.Errorf("invalid relative time %q, expected now-<duration>", value)
+		}
+		return now.Add(-d), nil
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339, unix seconds or now-<duration>", value)
+	}
+	return t, nil
+}
+
+// timeRangeFromQuery reads an optional time range from the given query parameters
+func timeRangeFromQuery(q url.Values, fromKey, toKey string, now time.Time) (*timestamppb.Timestamp, *timestamppb.Timestamp, error) {
+	var start, end *timestamppb.Timestamp
+	if from := q.Get(fromKey); from != "" {
+		t, err := parseTime(from, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s : %w", fromKey, err)
+		}
+		start = timestamppb.New(t)
+	}
+	if to := q.Get(toKey); to != "" {
+		t, err := parseTime(to, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s : %w", toKey, err)
+		}
+		end = timestamppb.New(t)
+	}
+	if start != nil && end != nil && end.AsTime().Before(start.AsTime()) {
+		return nil, nil, fmt.Errorf("%s must be after %s", toKey, fromKey)
+	}
+	return start, end, nil
+}
+
+// baseFromQuery reads the base_instance, base_selector, base_from & base_to query parameters
+// used to display diff profiles. The pprof web UI copies unknown query parameters into its navigation links.
+func baseFromQuery(q url.Values, now time.Time) (*db.BaseProfile, error) {
+	if !q.Has("base_instance") && !q.Has("base_selector") && !q.Has("base_from") && !q.Has("base_to") {
+		return nil, nil
+	}
+	start, end, err := timeRangeFromQuery(q, "base_from", "base_to", now)
+	if err != nil {
+		return nil, err
+	}
+	return &db.BaseProfile{
+		InstanceId: q.Get("base_instance"),
+		Selector:   q.Get("base_selector"),
+		Start:      start,
+		End:        end,
+	}, nil
 }
 
 // serves on /ui/<id>/<profile_type>
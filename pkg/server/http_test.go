@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	"github.com/google/pprof/profile"
+)
+
+func TestDisplayProfileEscapedId(t *testing.T) {
+	store := mem.NewProfileMemStorage()
+	for _, id := range []string{"ns/pod", "api"} {
+		prof := cpuProfile(time.Now().Add(-time.Minute), map[string]int64{"main.work": 2})
+		if err := store.Put(context.Background(), id, "cpu", nil, []*profile.Profile{prof}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := newTestHttpServer(t, store)
+	get := func(path string) (int, string) {
+		rec := httptest.NewRecorder()
+		p.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		body, _ := io.ReadAll(rec.Result().Body)
+		return rec.Code, string(body)
+	}
+
+	if _, body := get("/"); !strings.Contains(body, `href="/ui/ns%2Fpod/cpu/"`) {
+		t.Errorf("expected the index to link to the escaped id, got\n%s", body)
+	}
+
+	testCases := []struct {
+		name string
+		path string
+		code int
+	}{
+		{name: "escaped slash", path: "/ui/ns%2Fpod/cpu/top", code: http.StatusOK},
+		{name: "cached escaped slash", path: "/ui/ns%2Fpod/cpu/flamegraph", code: http.StatusOK},
+		{name: "plain id", path: "/ui/api/cpu/top", code: http.StatusOK},
+		{name: "unescaped slash", path: "/ui/ns/pod/cpu/top", code: http.StatusNotFound},
+		{name: "missing profile type", path: "/ui/api", code: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, body := get(tc.path)
+			if code != tc.code {
+				t.Fatalf("expected %d, got %d : %s", tc.code, code, body)
+			}
+			if code == http.StatusOK && !strings.Contains(body, "main.work") {
+				t.Errorf("expected the web UI to show main.work, got\n%s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var indexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"uiPath": func(id, pType string) string {
+		return "/ui/" + url.PathEscape(id) + "/" + url.PathEscape(pType) + "/"
+	},
+	"formatTime": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pprof-server</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+.label { display: inline-block; background: #eef; border-radius: 3px; padding: 0 4px; margin: 1px; font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>pprof-server</h1>
+{{- if not .Instances }}
+<p>No profiles stored yet.</p>
+{{- else }}
+<table>
+<tr><th>Instance</th><th>Labels</th><th>Profile type</th><th>Profiles</th><th>First seen</th><th>Last seen</th></tr>
+{{- range .Instances }}
+{{- $inst := . }}
+{{- range $i, $t := .Types }}
+<tr>
+{{- if eq $i 0 }}
+<td rowspan="{{ len $inst.Types }}">{{ $inst.Id }}</td>
+<td rowspan="{{ len $inst.Types }}">{{ range $inst.Labels }}<span class="label">{{ .Name }}={{ .Value }}</span> {{ end }}</td>
+{{- end }}
+<td><a href="{{ uiPath $inst.Id $t.Type }}">{{ $t.Type }}</a></td>
+<td>{{ $t.Count }}</td>
+<td>{{ formatTime $t.FirstSeen }}</td>
+<td>{{ formatTime $t.LastSeen }}</td>
+</tr>
+{{- end }}
+{{- end }}
+</table>
+{{- end }}
+</body>
+</html>
+`))
+
+type indexLabel struct {
+	Name, Value string
+}
+
+type indexType struct {
+	Type                string
+	Count               int64
+	FirstSeen, LastSeen time.Time
+}
+
+type indexInstance struct {
+	Id     string
+	Labels []indexLabel
+	Types  []indexType
+}
+
+// index lists the stored instances and their profile types, linking to the pprof web UI of each
+func (p *PprofHttpServer) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	instances, err := p.dbClient.ListInstances(r.Context(), &db.ListInstancesRequest{})
+	if err != nil {
+		logrus.WithError(err).Error("failed to list instances")
+		http.Error(w, "failed to list instances", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Instances []indexInstance
+	}{}
+	for _, inst := range instances.GetInstances() {
+		types, err := p.dbClient.ListProfileTypes(r.Context(), &db.ListProfileTypesRequest{
+			InstanceId: inst.InstanceId,
+		})
+		if status.Code(err) == codes.NotFound {
+			// evicted, compacted away or deleted since it was listed
+			continue
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("failed to list profile types of %s", inst.InstanceId)
+			http.Error(w, "failed to list profile types", http.StatusInternalServerError)
+			return
+		}
+		if len(types.GetTypes()) == 0 {
+			continue
+		}
+		entry := indexInstance{
+			Id: inst.InstanceId,
+		}
+		for name, value := range inst.Labels {
+			entry.Labels = append(entry.Labels, indexLabel{Name: name, Value: value})
+		}
+		sort.Slice(entry.Labels, func(i, j int) bool {
+			return entry.Labels[i].Name < entry.Labels[j].Name
+		})
+		for _, t := range types.GetTypes() {
+			entry.Types = append(entry.Types, indexType{
+				Type:      t.Type,
+				Count:     t.Count,
+				FirstSeen: t.FirstSeen.AsTime(),
+				LastSeen:  t.LastSeen.AsTime(),
+			})
+		}
+		data.Instances = append(data.Instances, entry)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, data); err != nil {
+		logrus.WithError(err).Error("failed to render index")
+	}
+}
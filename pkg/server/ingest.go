@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/google/pprof/profile"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/status"
+)
+
+// query parameters of /ingest, every other query parameter is stored as a label
+var ingestParams = map[string]struct{}{
+	"instance":   {},
+	"type":       {},
+	"from":       {},
+	"to":         {},
+	"cumulative": {},
+}
+
+// ingestProfile serves /ingest?instance=<id>&type=<profile_type>[&from=<time>&to=<time>][&cumulative=true][&<label>=<value>...],
+// accepting a raw, optionally gzipped, pprof body. Profiles are stored through the DB Put RPC.
+func (p *PprofHttpServer) ingestProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	id, pType := q.Get("instance"), q.Get("type")
+	if id == "" {
+		http.Error(w, "instance is required", http.StatusBadRequest)
+		return
+	}
+	if pType == "" {
+		http.Error(w, "type is required", http.StatusBadRequest)
+		return
+	}
+
+	cumulative := false
+	if c := q.Get("cumulative"); c != "" {
+		var err error
+		cumulative, err = strconv.ParseBool(c)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid cumulative %q", c), http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxOTLPBodySize+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(data) > maxOTLPBodySize {
+		http.Error(w, fmt.Sprintf("body exceeds %d bytes", maxOTLPBodySize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	prof, err := profile.ParseData(data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse profile : %s", err), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if from := q.Get("from"); from != "" {
+		start, err := parseTime(from, now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prof.TimeNanos = start.UnixNano()
+	}
+	if to := q.Get("to"); to != "" {
+		end, err := parseTime(to, now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if end.UnixNano() < prof.TimeNanos {
+			http.Error(w, "to must be after from", http.StatusBadRequest)
+			return
+		}
+		prof.DurationNanos = end.UnixNano() - prof.TimeNanos
+	}
+	if err := prof.CheckValid(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid profile : %s", err), http.StatusBadRequest)
+		return
+	}
+	b := &bytes.Buffer{}
+	if err := prof.Write(b); err != nil {
+		http.Error(w, "failed to encode profile", http.StatusInternalServerError)
+		return
+	}
+
+	labels := map[string]string{}
+	for key, values := range q {
+		if _, ok := ingestParams[key]; ok || len(values) == 0 {
+			continue
+		}
+		labels[key] = values[len(values)-1]
+	}
+	resp, err := p.dbClient.Put(r.Context(), &db.PutRequest{
+		InstanceId: id,
+		Type:       pType,
+		Labels:     labels,
+		Data:       b.Bytes(),
+		Cumulative: cumulative,
+	})
+	if err != nil {
+		logrus.WithError(err).Error("failed to ingest profile")
+		http.Error(w, "failed to ingest profile : "+status.Convert(err).Message(), forwardedStatus(err))
+		return
+	}
+	if resp.Baseline {
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "cumulative profile only serves as the baseline of the next delta")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	"google.golang.org/grpc"
+)
+
+// serverClient calls the DB server in process
+type serverClient struct {
+	db.DBClient
+	s *PprofServer
+}
+
+func (c *serverClient) Put(ctx context.Context, req *db.PutRequest, _ ...grpc.CallOption) (*db.PutResponse, error) {
+	return c.s.Put(ctx, req)
+}
+
+func TestIngestProfile(t *testing.T) {
+	start := time.Unix(1000, 0)
+	testCases := []struct {
+		name  string
+		query string
+		data  [][]byte
+		codes []int
+	}{
+		{
+			name:  "stored as is",
+			query: "instance=ci&type=mutex",
+			data:  [][]byte{mutexData(t, start, 5)},
+			codes: []int{http.StatusNoContent},
+		},
+		{
+			name:  "cumulative baseline",
+			query: "instance=ci&type=mutex&cumulative=true",
+			data:  [][]byte{mutexData(t, start, 5), mutexData(t, start.Add(time.Minute), 8)},
+			codes: []int{http.StatusAccepted, http.StatusNoContent},
+		},
+		{
+			name:  "invalid cumulative",
+			query: "instance=ci&type=mutex&cumulative=maybe",
+			data:  [][]byte{mutexData(t, start, 5)},
+			codes: []int{http.StatusBadRequest},
+		},
+		{
+			name:  "invalid profile",
+			query: "instance=ci&type=mutex",
+			data:  [][]byte{[]byte("garbage")},
+			codes: []int{http.StatusBadRequest},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewHttpServer("", &serverClient{s: NewPprofServer(mem.NewProfileMemStorage())}, nil)
+			for i, data := range tc.data {
+				w := httptest.NewRecorder()
+				p.ingestProfile(w, httptest.NewRequest(http.MethodPost, "/ingest?"+tc.query, bytes.NewReader(data)))
+				if w.Code != tc.codes[i] {
+					t.Errorf("expected upload %d to return %d, got %d : %s", i, tc.codes[i], w.Code, w.Body)
+				}
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"strings"
 
 	// pprofpb "github.com/alexandreLamarre/pprof-server/pkg/api/pprof"
 	"github.com/alexandreLamarre/otelbpf/receiver/pprofreceiver"
@@ -12,7 +11,9 @@ import (
 	"github.com/sirupsen/logrus"
 	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 	otlpcommonv1 "go.opentelemetry.io/proto/otlp/common/v1"
-	"google.golang.org/protobuf/encoding/protojson"
+	otlplogsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ collogspb.LogsServiceServer = (*PprofServer)(nil)
@@ -28,10 +29,12 @@ func parseMetadata(attr []*otlpcommonv1.KeyValue) (pprofreceiver.Metadata, map[s
 			md.ProfileType = kv.Value.GetStringValue()
 		case "pprof_host":
 			md.Host = kv.Value.GetStringValue()
+			rawMd["host"] = md.Host
 		case "pprof_port":
 			md.Port = kv.Value.GetStringValue()
+			rawMd["port"] = md.Port
 		default:
-			rawMd[kv.Key] = kv.Value.GetStringValue()
+			rawMd[kv.Key] = attributeValue(kv.Value)
 		}
 	}
 	if md.Id == "" {
@@ -44,61 +47,91 @@ func parseMetadata(attr []*otlpcommonv1.KeyValue) (pprofreceiver.Metadata, map[s
 }
 
 func (p *PprofServer) Export(ctx context.Context, request *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	var stored, rejected int64
+	errMsg := ""
 	for _, rscL := range request.GetResourceLogs() {
 		for _, scopeL := range rscL.GetScopeLogs() {
 			for _, record := range scopeL.GetLogRecords() {
-
 				recordMd := record.GetAttributes()
 				scopeMd := scopeL.GetScope().GetAttributes()
 				rscMd := rscL.GetResource().GetAttributes()
 
-				allAttributes := append(append(recordMd, scopeMd...), rscMd...)
+				allAttributes := make([]*otlpcommonv1.KeyValue, 0, len(recordMd)+len(scopeMd)+len(rscMd))
+				allAttributes = append(append(append(allAttributes, recordMd...), scopeMd...), rscMd...)
 
-				// ====== start debug stuff
-				res := []string{}
-				for _, attr := range allAttributes {
-					data, err := protojson.Marshal(attr)
-					if err != nil {
-						logrus.Errorf("Failed to marshal attribute: %v", err)
-						continue
-					}
-					res = append(res, string(data))
+				err := p.exportRecord(ctx, allAttributes, record)
+				if err == nil {
+					stored++
+					continue
+				}
+				// the collector retries the whole request, which is only safe while nothing was stored :
+				// records failing after that are reported as rejected
+				if retryable(err) && stored == 0 {
+					logrus.Errorf("Failed to store profile, asking the client to retry: %v", err)
+					return nil, err
+				}
+				logrus.Errorf("Rejected log record: %v", err)
+				rejected++
+				if errMsg == "" {
+					errMsg = status.Convert(err).Message()
 				}
+			}
+		}
+	}
 
-				logrus.Infof("Got attributes : %s", strings.Join(res, ","))
-				// ======= end debug stuff
+	resp := &collogspb.ExportLogsServiceResponse{}
+	if rejected > 0 {
+		resp.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: rejected,
+			ErrorMessage:       rejectedMessage(errMsg, rejected, "records"),
+		}
+	}
+	return resp, nil
+}
 
-				pMd, md, err := parseMetadata(allAttributes)
-				if err != nil {
-					logrus.Errorf("Failed to parse metadata: %v", err)
-					continue
-				}
+// rejectedMessage summarizes the errors of rejected records from the first error
+func rejectedMessage(errMsg string, rejected int64, records string) string {
+	if rejected > 1 {
+		return fmt.Sprintf("%s (and %d more rejected %s)", errMsg, rejected-1, records)
+	}
+	return errMsg
+}
 
-				body := record.GetBody().GetBytesValue()
-				if len(body) == 0 {
-					logrus.Warn("Received empty log record")
-					continue
-				}
+// exportRecord stores the profile held by a log record
+func (p *PprofServer) exportRecord(ctx context.Context, attributes []*otlpcommonv1.KeyValue, record *otlplogsv1.LogRecord) error {
+	pMd, md, err := parseMetadata(attributes)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
 
-				r := bytes.NewReader(body)
-				prof, err := profile.Parse(r)
-				if err != nil {
-					logrus.Errorf("Failed to parse profile: %v", err)
-					continue
-				}
+	body := record.GetBody().GetBytesValue()
+	if len(body) == 0 {
+		return status.Error(codes.InvalidArgument, "empty log record body, expected a pprof profile")
+	}
 
-				if valid := prof.CheckValid(); valid != nil {
-					logrus.Errorf("Invalid profile: %v", valid)
-					continue
-				}
+	r := bytes.NewReader(body)
+	prof, err := profile.Parse(r)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to parse profile : %s", err)
+	}
 
-				if err := p.store.Put(ctx, pMd.Id, pMd.ProfileType, md, []*profile.Profile{prof}); err != nil {
-					logrus.Errorf("Failed to store profile: %v", err)
-					continue
-				}
-			}
-		}
+	if valid := prof.CheckValid(); valid != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid profile : %s", valid)
 	}
 
-	return &collogspb.ExportLogsServiceResponse{}, nil
+	if err := p.Ingest(ctx, pMd.Id, pMd.ProfileType, md, prof); err != nil {
+		return status.Errorf(status.Code(err), "failed to store profile : %s", status.Convert(err).Message())
+	}
+	return nil
+}
+
+// retryable reports whether the collector should retry an export after this error,
+// rejected records are reported through partial success instead
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
 }
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	"github.com/google/pprof/profile"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	otlpcommonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	otlplogsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	otlpresourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unavailableStore fails every Put once healthy puts have been stored
+type unavailableStore struct {
+	storage.ProfileStore
+	healthy int
+}
+
+func (u *unavailableStore) Put(ctx context.Context, instanceId, profileType string, metadata map[string]string, profs []*profile.Profile) error {
+	if u.healthy == 0 {
+		return status.Error(codes.Unavailable, "store is unavailable")
+	}
+	u.healthy--
+	return u.ProfileStore.Put(ctx, instanceId, profileType, metadata, profs)
+}
+
+func encodedProfile(t *testing.T) []byte {
+	t.Helper()
+	fn := &profile.Function{ID: 1, Name: "main.work"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn}}}
+	prof := &profile.Profile{
+		SampleType:    []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		PeriodType:    &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		TimeNanos:     time.Now().UnixNano(),
+		DurationNanos: (10 * time.Second).Nanoseconds(),
+		Function:      []*profile.Function{fn},
+		Location:      []*profile.Location{loc},
+		Sample:        []*profile.Sample{{Location: []*profile.Location{loc}, Value: []int64{1}}},
+	}
+	b := &bytes.Buffer{}
+	if err := prof.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func logRecord(id string, body []byte) *otlplogsv1.LogRecord {
+	attributes := []*otlpcommonv1.KeyValue{}
+	if id != "" {
+		attributes = append(attributes, stringValue("pprof_id", id))
+	}
+	return &otlplogsv1.LogRecord{
+		Attributes: attributes,
+		Body: &otlpcommonv1.AnyValue{
+			Value: &otlpcommonv1.AnyValue_BytesValue{BytesValue: body},
+		},
+	}
+}
+
+func stringValue(key, value string) *otlpcommonv1.KeyValue {
+	return &otlpcommonv1.KeyValue{
+		Key: key,
+		Value: &otlpcommonv1.AnyValue{
+			Value: &otlpcommonv1.AnyValue_StringValue{StringValue: value},
+		},
+	}
+}
+
+func exportRequest(records ...*otlplogsv1.LogRecord) *collogspb.ExportLogsServiceRequest {
+	return &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*otlplogsv1.ResourceLogs{
+			{
+				Resource: &otlpresourcev1.Resource{
+					Attributes: []*otlpcommonv1.KeyValue{stringValue("pprof_profile_type", "cpu")},
+				},
+				ScopeLogs: []*otlplogsv1.ScopeLogs{
+					{LogRecords: records},
+				},
+			},
+		},
+	}
+}
+
+func TestExport(t *testing.T) {
+	data := encodedProfile(t)
+	testCases := []struct {
+		name     string
+		healthy  int
+		records  []*otlplogsv1.LogRecord
+		code     codes.Code
+		rejected int64
+	}{
+		{
+			name:    "all stored",
+			healthy: 2,
+			records: []*otlplogsv1.LogRecord{logRecord("a", data), logRecord("b", data)},
+		},
+		{
+			name:     "invalid records are rejected",
+			healthy:  2,
+			records:  []*otlplogsv1.LogRecord{logRecord("a", data), logRecord("", data), logRecord("b", []byte("garbage")), logRecord("c", nil)},
+			rejected: 3,
+		},
+		{
+			name:    "unavailable before storing anything is retryable",
+			healthy: 0,
+			records: []*otlplogsv1.LogRecord{logRecord("a", data), logRecord("b", data)},
+			code:    codes.Unavailable,
+		},
+		{
+			name:     "unavailable after storing records is a partial success",
+			healthy:  1,
+			records:  []*otlplogsv1.LogRecord{logRecord("a", data), logRecord("b", data), logRecord("c", data)},
+			rejected: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPprofServer(&unavailableStore{
+				ProfileStore: mem.NewProfileMemStorage(),
+				healthy:      tc.healthy,
+			})
+			resp, err := p.Export(context.Background(), exportRequest(tc.records...))
+			if status.Code(err) != tc.code {
+				t.Fatalf("expected %s, got %v", tc.code, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := resp.GetPartialSuccess().GetRejectedLogRecords(); got != tc.rejected {
+				t.Errorf("expected %d rejected records, got %d", tc.rejected, got)
+			}
+			if tc.rejected > 0 && resp.GetPartialSuccess().GetErrorMessage() == "" {
+				t.Error("expected an error message for rejected records")
+			}
+		})
+	}
+}
+
+func TestExportDoesNotModifyAttributes(t *testing.T) {
+	record := logRecord("a", encodedProfile(t))
+	// spare capacity that appending the scope & resource attributes could write into
+	record.Attributes = append(make([]*otlpcommonv1.KeyValue, 0, 8), record.Attributes...)
+	req := exportRequest(record)
+	if _, err := NewPprofServer(mem.NewProfileMemStorage()).Export(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if got := record.Attributes[:cap(record.Attributes)][1]; got != nil {
+		t.Errorf("record attributes backing array was modified : %v", got)
+	}
+}
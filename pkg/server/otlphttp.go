@@ -0,0 +1,147 @@
+package server
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+
+	// maxOTLPBodySize bounds the decompressed size of OTLP/HTTP requests
+	maxOTLPBodySize = 64 * 1024 * 1024
+
+	// MaxGRPCMessageSize is the message size the gRPC server and the HTTP server's client must accept,
+	// so bodies accepted over HTTP are not rejected when forwarded over gRPC
+	MaxGRPCMessageSize = maxOTLPBodySize + 1024*1024
+)
+
+// httpStatusFromGRPC maps gRPC status codes to HTTP status codes, following the OTLP/HTTP specification
+func httpStatusFromGRPC(err error) int {
+	switch status.Code(err) {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable, codes.Aborted:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// forwardedStatus maps the error of a request forwarded over gRPC to an HTTP status code.
+// Forwarded messages exceeding the gRPC message size limit are reported as too large rather than
+// as a retryable 429, clients would otherwise resend them forever.
+func forwardedStatus(err error) int {
+	if status.Code(err) == codes.ResourceExhausted {
+		return http.StatusRequestEntityTooLarge
+	}
+	return httpStatusFromGRPC(err)
+}
+
+// readOTLPBody decodes a protobuf or JSON encoded OTLP/HTTP request body, optionally gzip compressed
+func readOTLPBody(r *http.Request, msg proto.Message) (string, int, error) {
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (contentType != contentTypeProtobuf && contentType != contentTypeJSON) {
+		return "", http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", r.Header.Get("Content-Type"))
+	}
+
+	var body io.Reader = r.Body
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return contentType, http.StatusBadRequest, fmt.Errorf("invalid gzip body : %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		return contentType, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content encoding %q", r.Header.Get("Content-Encoding"))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxOTLPBodySize+1))
+	if err != nil {
+		return contentType, http.StatusBadRequest, fmt.Errorf("failed to read body : %w", err)
+	}
+	if len(data) > maxOTLPBodySize {
+		return contentType, http.StatusRequestEntityTooLarge, fmt.Errorf("body exceeds %d bytes", maxOTLPBodySize)
+	}
+
+	if contentType == contentTypeJSON {
+		err = protojson.Unmarshal(data, msg)
+	} else {
+		err = proto.Unmarshal(data, msg)
+	}
+	if err != nil {
+		return contentType, http.StatusBadRequest, fmt.Errorf("failed to decode body : %w", err)
+	}
+	return contentType, http.StatusOK, nil
+}
+
+func writeOTLPResponse(w http.ResponseWriter, contentType string, code int, msg proto.Message) {
+	var data []byte
+	var err error
+	if contentType == contentTypeJSON {
+		data, err = protojson.Marshal(msg)
+	} else {
+		contentType = contentTypeProtobuf
+		data, err = proto.Marshal(msg)
+	}
+	if err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(code)
+	if _, err := w.Write(data); err != nil {
+		logrus.WithError(err).Warn("failed to write OTLP response")
+	}
+}
+
+// exportLogs serves OTLP/HTTP log exports on /v1/logs, forwarding them to the gRPC logs service
+func (p *PprofHttpServer) exportLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	req := &collogspb.ExportLogsServiceRequest{}
+	contentType, code, err := readOTLPBody(r, req)
+	if err != nil {
+		writeOTLPResponse(w, contentType, code, status.New(codes.InvalidArgument, err.Error()).Proto())
+		return
+	}
+
+	resp, err := p.logsClient.Export(r.Context(), req)
+	if err != nil {
+		logrus.WithError(err).Error("failed to export logs")
+		writeOTLPResponse(w, contentType, forwardedStatus(err), status.Convert(err).Proto())
+		return
+	}
+	writeOTLPResponse(w, contentType, http.StatusOK, resp)
+}
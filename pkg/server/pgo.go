@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (p *PprofServer) PGO(ctx context.Context, req *db.PGORequest) (*db.GetProfileResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	prof, err := p.query(ctx, req.InstanceId, req.Selector, req.Type, req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := pgoProfile(prof, req.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	data, err := encodeProfile(ret, db.Format_FORMAT_PPROF, "")
+	if err != nil {
+		return nil, err
+	}
+	return &db.GetProfileResponse{
+		Data: data,
+	}, nil
+}
+
+// pgoProfile keeps what `go build -pgo` reads from a cpu profile : a single sample value,
+// function names, start lines and line numbers. Labels, addresses, mappings & comments are dropped,
+// as well as samples below threshold percent of the total.
+func pgoProfile(prof *profile.Profile, threshold float64) (*profile.Profile, error) {
+	idx := -1
+	for i, st := range prof.SampleType {
+		// the go toolchain accepts either of them
+		if (st.Type == "samples" && st.Unit == "count") || (st.Type == "cpu" && st.Unit == "nanoseconds") {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return nil, status.Error(codes.FailedPrecondition, "profile has no samples/count or cpu/nanoseconds sample type, PGO requires cpu profiles")
+	}
+
+	var total int64
+	for _, s := range prof.Sample {
+		total += s.Value[idx]
+	}
+	cutoff := threshold / 100 * float64(total)
+
+	ret := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: prof.SampleType[idx].Type, Unit: prof.SampleType[idx].Unit},
+		},
+		PeriodType:    prof.PeriodType,
+		Period:        prof.Period,
+		TimeNanos:     prof.TimeNanos,
+		DurationNanos: prof.DurationNanos,
+	}
+	functions := map[*profile.Function]*profile.Function{}
+	function := func(fn *profile.Function) *profile.Function {
+		if f, ok := functions[fn]; ok {
+			return f
+		}
+		f := &profile.Function{
+			ID:        uint64(len(ret.Function) + 1),
+			Name:      fn.Name,
+			Filename:  fn.Filename,
+			StartLine: fn.StartLine,
+		}
+		functions[fn] = f
+		ret.Function = append(ret.Function, f)
+		return f
+	}
+	locations := map[*profile.Location]*profile.Location{}
+	location := func(loc *profile.Location) *profile.Location {
+		if l, ok := locations[loc]; ok {
+			return l
+		}
+		l := &profile.Location{
+			ID: uint64(len(ret.Location) + 1),
+		}
+		for _, line := range loc.Line {
+			if line.Function == nil {
+				continue
+			}
+			l.Line = append(l.Line, profile.Line{
+				Function: function(line.Function),
+				Line:     line.Line,
+			})
+		}
+		locations[loc] = l
+		ret.Location = append(ret.Location, l)
+		return l
+	}
+
+	for _, s := range prof.Sample {
+		v := s.Value[idx]
+		if v <= 0 || float64(v) < cutoff {
+			continue
+		}
+		sample := &profile.Sample{
+			Value: []int64{v},
+		}
+		for _, loc := range s.Location {
+			// frames without symbols carry no information for the compiler
+			if len(loc.Line) == 0 {
+				continue
+			}
+			sample.Location = append(sample.Location, location(loc))
+		}
+		if len(sample.Location) == 0 {
+			continue
+		}
+		ret.Sample = append(ret.Sample, sample)
+	}
+	// merges the samples & locations that only differed by their labels or addresses
+	ret = ret.Compact()
+	if err := ret.CheckValid(); err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid PGO profile : %s", err)
+	}
+	return ret, nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// labelledProfile adds what PGO profiles don't need to stackedProfile : mappings, addresses, labels & comments
+func labelledProfile() *profile.Profile {
+	prof := stackedProfile()
+	mapping := &profile.Mapping{ID: 1, Start: 0x1000, Limit: 0x2000, File: "/bin/app", HasFunctions: true}
+	prof.Mapping = []*profile.Mapping{mapping}
+	for _, loc := range prof.Location {
+		loc.Mapping = mapping
+		if loc.Address == 0 {
+			loc.Address = 0x1000 + loc.ID*0x10
+		}
+	}
+	for i, s := range prof.Sample {
+		s.Label = map[string][]string{"goroutine": {string(rune('a' + i))}}
+		s.NumLabel = map[string][]int64{"bytes": {int64(i)}}
+	}
+	for _, fn := range prof.Function {
+		fn.SystemName = fn.Name + ".abi0"
+	}
+	prof.Comments = []string{"built from main"}
+	return prof
+}
+
+func TestPGOProfile(t *testing.T) {
+	testCases := []struct {
+		name       string
+		sampleType []*profile.ValueType
+		threshold  float64
+		expected   string
+		code       codes.Code
+	}{
+		{
+			name:      "keeps every sample without threshold",
+			threshold: 0,
+			expected: "main.main 2\n" +
+				"main.main;main.work;encoding/json.Marshal 4\n" +
+				"main.main;pkg.(*T).a:b_c 4\n",
+		},
+		{
+			name:      "drops samples below the threshold",
+			threshold: 25,
+			expected: "main.main;main.work;encoding/json.Marshal 3\n" +
+				"main.main;pkg.(*T).a:b_c 4\n",
+		},
+		{
+			name:      "threshold compares each sample to the total",
+			threshold: 35,
+			expected:  "main.main;pkg.(*T).a:b_c 4\n",
+		},
+		{
+			name: "cpu nanoseconds",
+			sampleType: []*profile.ValueType{
+				{Type: "alloc_space", Unit: "bytes"},
+				{Type: "cpu", Unit: "nanoseconds"},
+			},
+			expected: "main.main 20\n" +
+				"main.main;main.work;encoding/json.Marshal 40\n",
+		},
+		{
+			name: "not a cpu profile",
+			sampleType: []*profile.ValueType{
+				{Type: "alloc_space", Unit: "bytes"},
+				{Type: "inuse_space", Unit: "bytes"},
+			},
+			code: codes.FailedPrecondition,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prof := labelledProfile()
+			if tc.sampleType != nil {
+				prof.SampleType = tc.sampleType
+			}
+			ret, err := pgoProfile(prof, tc.threshold)
+			if status.Code(err) != tc.code {
+				t.Fatalf("expected %s, got %v", tc.code, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(ret.SampleType) != 1 {
+				t.Errorf("expected a single sample type, got %v", ret.SampleType)
+			}
+			if len(ret.Mapping) != 0 || len(ret.Comments) != 0 {
+				t.Errorf("expected mappings & comments to be dropped, got %v %v", ret.Mapping, ret.Comments)
+			}
+			for _, loc := range ret.Location {
+				if loc.Address != 0 || loc.Mapping != nil {
+					t.Errorf("expected location %d to be stripped, got address %x mapping %v", loc.ID, loc.Address, loc.Mapping)
+				}
+			}
+			for _, fn := range ret.Function {
+				if fn.SystemName != "" {
+					t.Errorf("expected the system name of %s to be dropped", fn.Name)
+				}
+			}
+			for _, s := range ret.Sample {
+				if len(s.Label) != 0 || len(s.NumLabel) != 0 {
+					t.Errorf("expected labels to be dropped, got %v %v", s.Label, s.NumLabel)
+				}
+			}
+			// samples only differing by their labels or unsymbolized frames are merged
+			if lines := strings.Count(tc.expected, "\n"); len(ret.Sample) != lines {
+				t.Errorf("expected %d samples, got %d", lines, len(ret.Sample))
+			}
+			if got := string(folded(ret, 0)); got != tc.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/google/pprof/profile"
+	"github.com/sirupsen/logrus"
+	colprofilespb "go.opentelemetry.io/proto/otlp/collector/profiles/v1experimental"
+	otlpcommonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	otlpprofilesv1 "go.opentelemetry.io/proto/otlp/profiles/v1experimental"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// otlpProfilesServer receives the native OTLP profiles signal. Its Export method
+// conflicts with the logs service's, so it is served by a separate type sharing the PprofServer's storage.
+type otlpProfilesServer struct {
+	colprofilespb.UnsafeProfilesServiceServer
+
+	p *PprofServer
+}
+
+var _ colprofilespb.ProfilesServiceServer = (*otlpProfilesServer)(nil)
+
+// ProfilesServer returns the OTLP profiles service, storing profiles in the same store as p
+func (p *PprofServer) ProfilesServer() colprofilespb.ProfilesServiceServer {
+	return &otlpProfilesServer{
+		p: p,
+	}
+}
+
+func attributeValue(v *otlpcommonv1.AnyValue) string {
+	switch val := v.GetValue().(type) {
+	case *otlpcommonv1.AnyValue_StringValue:
+		return val.StringValue
+	case *otlpcommonv1.AnyValue_IntValue:
+		return strconv.FormatInt(val.IntValue, 10)
+	case *otlpcommonv1.AnyValue_BoolValue:
+		return strconv.FormatBool(val.BoolValue)
+	case *otlpcommonv1.AnyValue_DoubleValue:
+		return strconv.FormatFloat(val.DoubleValue, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// parseProfileMetadata reads the same `pprof_*` attributes as log-wrapped profiles, falling back to
+// OpenTelemetry semantic conventions for the instance id and to the profile's period type for the profile type.
+func parseProfileMetadata(attr []*otlpcommonv1.KeyValue, prof *profile.Profile) (string, string, map[string]string, error) {
+	pMd, md, _ := parseMetadata(attr)
+	id, profileType := pMd.Id, pMd.ProfileType
+	if id == "" {
+		id = md["service.instance.id"]
+	}
+	if id == "" {
+		id = md["service.name"]
+	}
+	if profileType == "" && prof.PeriodType != nil {
+		profileType = prof.PeriodType.Type
+	}
+	if profileType == "" && len(prof.SampleType) > 0 {
+		profileType = prof.SampleType[0].Type
+	}
+	if id == "" {
+		return "", "", md, fmt.Errorf("missing id, unable to persist sample profile")
+	}
+	if profileType == "" {
+		return "", "", md, fmt.Errorf("missing profile type, unable to persist sample profile")
+	}
+	return id, profileType, md, nil
+}
+
+func (o *otlpProfilesServer) Export(ctx context.Context, request *colprofilespb.ExportProfilesServiceRequest) (*colprofilespb.ExportProfilesServiceResponse, error) {
+	var stored, rejected int64
+	errMsg := ""
+	for _, rscP := range request.GetResourceProfiles() {
+		for _, scopeP := range rscP.GetScopeProfiles() {
+			for _, container := range scopeP.GetProfiles() {
+				recordMd := container.GetAttributes()
+				scopeMd := scopeP.GetScope().GetAttributes()
+				rscMd := rscP.GetResource().GetAttributes()
+
+				allAttributes := make([]*otlpcommonv1.KeyValue, 0, len(recordMd)+len(scopeMd)+len(rscMd))
+				allAttributes = append(append(append(allAttributes, recordMd...), scopeMd...), rscMd...)
+
+				err := o.exportProfile(ctx, allAttributes, container)
+				if err == nil {
+					stored++
+					continue
+				}
+				// like log exports, retrying the whole request is only safe while nothing was stored
+				if retryable(err) && stored == 0 {
+					logrus.Errorf("Failed to store profile, asking the client to retry: %v", err)
+					return nil, err
+				}
+				logrus.Errorf("Rejected profile: %v", err)
+				rejected++
+				if errMsg == "" {
+					errMsg = status.Convert(err).Message()
+				}
+			}
+		}
+	}
+
+	resp := &colprofilespb.ExportProfilesServiceResponse{}
+	if rejected > 0 {
+		resp.PartialSuccess = &colprofilespb.ExportProfilesPartialSuccess{
+			RejectedProfiles: rejected,
+			ErrorMessage:     rejectedMessage(errMsg, rejected, "profiles"),
+		}
+	}
+	return resp, nil
+}
+
+// exportProfile stores an OTLP profile
+func (o *otlpProfilesServer) exportProfile(ctx context.Context, attributes []*otlpcommonv1.KeyValue, container *otlpprofilesv1.ProfileContainer) error {
+	prof, err := convertOTLPProfile(container)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to convert profile : %s", err)
+	}
+
+	if valid := prof.CheckValid(); valid != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid profile : %s", valid)
+	}
+
+	id, profileType, md, err := parseProfileMetadata(attributes, prof)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := o.p.Ingest(ctx, id, profileType, md, prof); err != nil {
+		return status.Errorf(status.Code(err), "failed to store profile : %s", status.Convert(err).Message())
+	}
+	return nil
+}
+
+// convertOTLPProfile converts the pprof-extended OTLP profile into a pprof profile.
+// OTLP profiles reference mappings, locations and functions by index instead of by id.
+func convertOTLPProfile(container *otlpprofilesv1.ProfileContainer) (*profile.Profile, error) {
+	src := container.GetProfile()
+	if src == nil {
+		return nil, fmt.Errorf("missing profile")
+	}
+	strs := src.GetStringTable()
+	str := func(i int64) (string, error) {
+		if i < 0 || i >= int64(len(strs)) {
+			return "", fmt.Errorf("string index %d out of range", i)
+		}
+		return strs[i], nil
+	}
+	var err error
+	valueType := func(vt *otlpprofilesv1.ValueType) *profile.ValueType {
+		if vt == nil || err != nil {
+			return nil
+		}
+		ret := &profile.ValueType{}
+		if ret.Type, err = str(vt.Type); err != nil {
+			return nil
+		}
+		if ret.Unit, err = str(vt.Unit); err != nil {
+			return nil
+		}
+		return ret
+	}
+
+	dst := &profile.Profile{
+		TimeNanos:     src.TimeNanos,
+		DurationNanos: src.DurationNanos,
+		Period:        src.Period,
+		PeriodType:    valueType(src.PeriodType),
+	}
+	if dst.TimeNanos == 0 {
+		dst.TimeNanos = int64(container.StartTimeUnixNano)
+	}
+	if dst.DurationNanos == 0 && container.EndTimeUnixNano > container.StartTimeUnixNano {
+		dst.DurationNanos = int64(container.EndTimeUnixNano - container.StartTimeUnixNano)
+	}
+	for _, st := range src.SampleType {
+		dst.SampleType = append(dst.SampleType, valueType(st))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dst.DropFrames, err = str(src.DropFrames); err != nil {
+		return nil, err
+	}
+	if dst.KeepFrames, err = str(src.KeepFrames); err != nil {
+		return nil, err
+	}
+	if dst.DefaultSampleType, err = str(src.DefaultSampleType); err != nil {
+		return nil, err
+	}
+	for _, c := range src.Comment {
+		comment, err := str(c)
+		if err != nil {
+			return nil, err
+		}
+		dst.Comments = append(dst.Comments, comment)
+	}
+
+	for i, m := range src.Mapping {
+		mapping := &profile.Mapping{
+			ID:              uint64(i + 1),
+			Start:           m.MemoryStart,
+			Limit:           m.MemoryLimit,
+			Offset:          m.FileOffset,
+			HasFunctions:    m.HasFunctions,
+			HasFilenames:    m.HasFilenames,
+			HasLineNumbers:  m.HasLineNumbers,
+			HasInlineFrames: m.HasInlineFrames,
+		}
+		if mapping.File, err = str(m.Filename); err != nil {
+			return nil, err
+		}
+		if mapping.BuildID, err = str(m.BuildId); err != nil {
+			return nil, err
+		}
+		dst.Mapping = append(dst.Mapping, mapping)
+	}
+
+	for i, f := range src.Function {
+		fn := &profile.Function{
+			ID:        uint64(i + 1),
+			StartLine: f.StartLine,
+		}
+		if fn.Name, err = str(f.Name); err != nil {
+			return nil, err
+		}
+		if fn.SystemName, err = str(f.SystemName); err != nil {
+			return nil, err
+		}
+		if fn.Filename, err = str(f.Filename); err != nil {
+			return nil, err
+		}
+		dst.Function = append(dst.Function, fn)
+	}
+
+	for i, l := range src.Location {
+		loc := &profile.Location{
+			ID:       uint64(i + 1),
+			Address:  l.Address,
+			IsFolded: l.IsFolded,
+		}
+		if len(dst.Mapping) > 0 {
+			if l.MappingIndex >= uint64(len(dst.Mapping)) {
+				return nil, fmt.Errorf("mapping index %d out of range", l.MappingIndex)
+			}
+			loc.Mapping = dst.Mapping[l.MappingIndex]
+		}
+		for _, line := range l.Line {
+			if line.FunctionIndex >= uint64(len(dst.Function)) {
+				return nil, fmt.Errorf("function index %d out of range", line.FunctionIndex)
+			}
+			loc.Line = append(loc.Line, profile.Line{
+				Function: dst.Function[line.FunctionIndex],
+				Line:     line.Line,
+			})
+		}
+		dst.Location = append(dst.Location, loc)
+	}
+
+	for _, s := range src.Sample {
+		sample := &profile.Sample{
+			Value: s.Value,
+		}
+		// locations_start_index & locations_length supersede the deprecated location_index
+		locationIndices := s.LocationIndex
+		if s.LocationsLength > 0 {
+			end := s.LocationsStartIndex + s.LocationsLength
+			if end > uint64(len(src.LocationIndices)) {
+				return nil, fmt.Errorf("sample locations [%d:%d] out of range", s.LocationsStartIndex, end)
+			}
+			locationIndices = make([]uint64, 0, s.LocationsLength)
+			for _, idx := range src.LocationIndices[s.LocationsStartIndex:end] {
+				locationIndices = append(locationIndices, uint64(idx))
+			}
+		}
+		for _, idx := range locationIndices {
+			if idx >= uint64(len(dst.Location)) {
+				return nil, fmt.Errorf("location index %d out of range", idx)
+			}
+			sample.Location = append(sample.Location, dst.Location[idx])
+		}
+		if err := convertSampleLabels(src, s, sample, str); err != nil {
+			return nil, err
+		}
+		dst.Sample = append(dst.Sample, sample)
+	}
+	return dst, nil
+}
+
+func convertSampleLabels(
+	src *otlpprofilesv1.Profile,
+	s *otlpprofilesv1.Sample,
+	dst *profile.Sample,
+	str func(int64) (string, error),
+) error {
+	addStr := func(key, value string) {
+		if dst.Label == nil {
+			dst.Label = map[string][]string{}
+		}
+		dst.Label[key] = append(dst.Label[key], value)
+	}
+	addNum := func(key string, value int64, unit string) {
+		if dst.NumLabel == nil {
+			dst.NumLabel = map[string][]int64{}
+			dst.NumUnit = map[string][]string{}
+		}
+		dst.NumLabel[key] = append(dst.NumLabel[key], value)
+		dst.NumUnit[key] = append(dst.NumUnit[key], unit)
+	}
+	for _, l := range s.Label {
+		key, err := str(l.Key)
+		if err != nil {
+			return err
+		}
+		if l.Str != 0 {
+			value, err := str(l.Str)
+			if err != nil {
+				return err
+			}
+			addStr(key, value)
+			continue
+		}
+		unit, err := str(l.NumUnit)
+		if err != nil {
+			return err
+		}
+		addNum(key, l.Num, unit)
+	}
+	for _, idx := range s.Attributes {
+		if idx >= uint64(len(src.AttributeTable)) {
+			return fmt.Errorf("attribute index %d out of range", idx)
+		}
+		kv := src.AttributeTable[idx]
+		if v, ok := kv.GetValue().GetValue().(*otlpcommonv1.AnyValue_IntValue); ok {
+			addNum(kv.Key, v.IntValue, "")
+			continue
+		}
+		addStr(kv.Key, attributeValue(kv.GetValue()))
+	}
+	return nil
+}
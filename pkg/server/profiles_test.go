@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	colprofilespb "go.opentelemetry.io/proto/otlp/collector/profiles/v1experimental"
+	otlpcommonv1 "go.opentelemetry.io/proto/otlp/common/v1"
+	otlpprofilesv1 "go.opentelemetry.io/proto/otlp/profiles/v1experimental"
+	otlpresourcev1 "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// otlpProfile builds a cpu profile where main.main calls main.work, sampled 3 times in a worker thread
+// through locations_start_index & locations_length, and once in main.main through the deprecated location_index
+func otlpProfile() *otlpprofilesv1.ProfileContainer {
+	start := time.Now().Add(-time.Minute)
+	return &otlpprofilesv1.ProfileContainer{
+		StartTimeUnixNano: uint64(start.UnixNano()),
+		EndTimeUnixNano:   uint64(start.Add(10 * time.Second).UnixNano()),
+		Profile: &otlpprofilesv1.Profile{
+			StringTable: []string{"", "samples", "count", "cpu", "nanoseconds", "main.main", "main.go", "main.work", "thread", "worker", "size", "bytes", "/bin/app"},
+			SampleType:  []*otlpprofilesv1.ValueType{{Type: 1, Unit: 2}},
+			PeriodType:  &otlpprofilesv1.ValueType{Type: 3, Unit: 4},
+			Period:      10000000,
+			Mapping:     []*otlpprofilesv1.Mapping{{MemoryStart: 0x1000, MemoryLimit: 0x2000, Filename: 12, HasFunctions: true}},
+			Function: []*otlpprofilesv1.Function{
+				{Name: 5, Filename: 6},
+				{Name: 7, Filename: 6},
+			},
+			Location: []*otlpprofilesv1.Location{
+				{Address: 0x1100, Line: []*otlpprofilesv1.Line{{FunctionIndex: 0, Line: 10}}},
+				{Address: 0x1200, Line: []*otlpprofilesv1.Line{{FunctionIndex: 1, Line: 20}}},
+			},
+			// leaf first
+			LocationIndices: []int64{1, 0},
+			AttributeTable: []*otlpcommonv1.KeyValue{
+				{Key: "pid", Value: &otlpcommonv1.AnyValue{Value: &otlpcommonv1.AnyValue_IntValue{IntValue: 42}}},
+				{Key: "container", Value: &otlpcommonv1.AnyValue{Value: &otlpcommonv1.AnyValue_StringValue{StringValue: "app"}}},
+			},
+			Sample: []*otlpprofilesv1.Sample{
+				{
+					LocationsStartIndex: 0,
+					LocationsLength:     2,
+					Value:               []int64{3},
+					Label:               []*otlpprofilesv1.Label{{Key: 8, Str: 9}},
+					Attributes:          []uint64{0, 1},
+				},
+				{
+					LocationIndex: []uint64{0},
+					Value:         []int64{1},
+					Label:         []*otlpprofilesv1.Label{{Key: 10, Num: 64, NumUnit: 11}},
+				},
+			},
+		},
+	}
+}
+
+func exportProfilesRequest(containers ...*otlpprofilesv1.ProfileContainer) *colprofilespb.ExportProfilesServiceRequest {
+	return &colprofilespb.ExportProfilesServiceRequest{
+		ResourceProfiles: []*otlpprofilesv1.ResourceProfiles{
+			{
+				Resource: &otlpresourcev1.Resource{
+					Attributes: []*otlpcommonv1.KeyValue{stringValue("service.name", "api")},
+				},
+				ScopeProfiles: []*otlpprofilesv1.ScopeProfiles{
+					{Profiles: containers},
+				},
+			},
+		},
+	}
+}
+
+func TestExportProfiles(t *testing.T) {
+	invalid := otlpProfile()
+	invalid.Profile.LocationIndices = nil
+	testCases := []struct {
+		name       string
+		healthy    int
+		containers []*otlpprofilesv1.ProfileContainer
+		code       codes.Code
+		rejected   int64
+	}{
+		{
+			name:       "all stored",
+			healthy:    2,
+			containers: []*otlpprofilesv1.ProfileContainer{otlpProfile(), otlpProfile()},
+		},
+		{
+			name:       "invalid profiles are rejected",
+			healthy:    2,
+			containers: []*otlpprofilesv1.ProfileContainer{otlpProfile(), invalid, {}},
+			rejected:   2,
+		},
+		{
+			name:       "unavailable before storing anything is retryable",
+			healthy:    0,
+			containers: []*otlpprofilesv1.ProfileContainer{otlpProfile(), otlpProfile()},
+			code:       codes.Unavailable,
+		},
+		{
+			name:       "unavailable after storing profiles is a partial success",
+			healthy:    1,
+			containers: []*otlpprofilesv1.ProfileContainer{otlpProfile(), otlpProfile(), otlpProfile()},
+			rejected:   2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPprofServer(&unavailableStore{
+				ProfileStore: mem.NewProfileMemStorage(),
+				healthy:      tc.healthy,
+			})
+			resp, err := p.ProfilesServer().Export(context.Background(), exportProfilesRequest(tc.containers...))
+			if status.Code(err) != tc.code {
+				t.Fatalf("expected %s, got %v", tc.code, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := resp.GetPartialSuccess().GetRejectedProfiles(); got != tc.rejected {
+				t.Errorf("expected %d rejected profiles, got %d", tc.rejected, got)
+			}
+			if tc.rejected > 0 && resp.GetPartialSuccess().GetErrorMessage() == "" {
+				t.Error("expected an error message for rejected profiles")
+			}
+		})
+	}
+}
+
+func TestConvertOTLPProfile(t *testing.T) {
+	prof, err := convertOTLPProfile(otlpProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := prof.CheckValid(); err != nil {
+		t.Fatal(err)
+	}
+	if prof.PeriodType.Type != "cpu" || prof.PeriodType.Unit != "nanoseconds" || prof.Period != 10000000 {
+		t.Errorf("unexpected period %v %d", prof.PeriodType, prof.Period)
+	}
+	if len(prof.SampleType) != 1 || prof.SampleType[0].Type != "samples" || prof.SampleType[0].Unit != "count" {
+		t.Errorf("unexpected sample types %v", prof.SampleType)
+	}
+	if prof.DurationNanos != (10 * time.Second).Nanoseconds() {
+		t.Errorf("expected the duration to default to the container's time range, got %d", prof.DurationNanos)
+	}
+	if len(prof.Sample) != 2 {
+		t.Fatalf("expected 2 samples, got %d", len(prof.Sample))
+	}
+
+	stack := func(s int) []string {
+		var ret []string
+		for _, loc := range prof.Sample[s].Location {
+			if loc.Mapping == nil || loc.Mapping.File != "/bin/app" {
+				t.Errorf("expected location %d to be mapped to /bin/app, got %v", loc.ID, loc.Mapping)
+			}
+			for _, line := range loc.Line {
+				ret = append(ret, line.Function.Name)
+			}
+		}
+		return ret
+	}
+	if got := stack(0); !slices.Equal(got, []string{"main.work", "main.main"}) {
+		t.Errorf("unexpected stack from locations_start_index & locations_length %v", got)
+	}
+	if got := stack(1); !slices.Equal(got, []string{"main.main"}) {
+		t.Errorf("unexpected stack from location_index %v", got)
+	}
+
+	if got := prof.Sample[0].Label; !reflect.DeepEqual(got, map[string][]string{"thread": {"worker"}, "container": {"app"}}) {
+		t.Errorf("unexpected string labels %v", got)
+	}
+	if got := prof.Sample[0].NumLabel; !reflect.DeepEqual(got, map[string][]int64{"pid": {42}}) {
+		t.Errorf("expected int attributes to be numeric labels, got %v", got)
+	}
+	if got, unit := prof.Sample[1].NumLabel, prof.Sample[1].NumUnit; !reflect.DeepEqual(got, map[string][]int64{"size": {64}}) ||
+		!reflect.DeepEqual(unit, map[string][]string{"size": {"bytes"}}) {
+		t.Errorf("unexpected numeric labels %v %v", got, unit)
+	}
+}
+
+func TestConvertOTLPProfileErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(c *otlpprofilesv1.ProfileContainer)
+	}{
+		{
+			name:   "missing profile",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile = nil },
+		},
+		{
+			name:   "sample type string out of range",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile.SampleType[0].Unit = 100 },
+		},
+		{
+			name:   "mapping index out of range",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile.Location[0].MappingIndex = 1 },
+		},
+		{
+			name:   "function index out of range",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile.Location[1].Line[0].FunctionIndex = 2 },
+		},
+		{
+			name:   "sample locations out of range",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile.Sample[0].LocationsStartIndex = 1 },
+		},
+		{
+			name:   "location index out of range",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile.Sample[1].LocationIndex = []uint64{2} },
+		},
+		{
+			name:   "label string out of range",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile.Sample[0].Label[0].Str = 100 },
+		},
+		{
+			name:   "label unit out of range",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile.Sample[1].Label[0].NumUnit = 100 },
+		},
+		{
+			name:   "attribute index out of range",
+			modify: func(c *otlpprofilesv1.ProfileContainer) { c.Profile.Sample[0].Attributes = []uint64{2} },
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := otlpProfile()
+			tc.modify(c)
+			if _, err := convertOTLPProfile(c); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
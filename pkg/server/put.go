@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (p *PprofServer) Put(ctx context.Context, req *db.PutRequest) (*db.PutResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	prof, err := profile.ParseData(req.Data)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse profile : %s", err)
+	}
+	if err := prof.CheckValid(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid profile : %s", err)
+	}
+	labels := req.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	// profiles are stored as is, unless the caller knows they hold totals since process start
+	isBaseline := false
+	if req.Cumulative {
+		isBaseline, err = p.ingestCumulative(ctx, req.InstanceId, req.Type, labels, prof)
+	} else {
+		err = p.store.Put(ctx, req.InstanceId, req.Type, labels, []*profile.Profile{prof})
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			return nil, status.Errorf(codes.Internal, "failed to store profile : %s", err)
+		}
+		return nil, err
+	}
+	return &db.PutResponse{Baseline: isBaseline}, nil
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func mutexData(t *testing.T, at time.Time, contentions int64) []byte {
+	t.Helper()
+	fn := &profile.Function{ID: 1, Name: "main.lock"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn}}}
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{{Type: "contentions", Unit: "count"}, {Type: "delay", Unit: "nanoseconds"}},
+		TimeNanos:  at.UnixNano(),
+		Function:   []*profile.Function{fn},
+		Location:   []*profile.Location{loc},
+		Sample:     []*profile.Sample{{Location: []*profile.Location{loc}, Value: []int64{contentions, 100 * contentions}}},
+	}
+	b := &bytes.Buffer{}
+	if err := prof.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestPut(t *testing.T) {
+	start := time.Unix(1000, 0)
+	testCases := []struct {
+		name       string
+		cumulative bool
+		data       [][]byte
+		code       codes.Code
+		baseline   []bool
+		stored     int
+	}{
+		{
+			name:     "stored as is",
+			data:     [][]byte{mutexData(t, start, 5)},
+			baseline: []bool{false},
+			stored:   1,
+		},
+		{
+			name:       "first cumulative profile is a baseline",
+			cumulative: true,
+			data:       [][]byte{mutexData(t, start, 5)},
+			baseline:   []bool{true},
+		},
+		{
+			name:       "cumulative profiles are stored as deltas",
+			cumulative: true,
+			data:       [][]byte{mutexData(t, start, 5), mutexData(t, start.Add(time.Minute), 8)},
+			baseline:   []bool{true, false},
+			stored:     1,
+		},
+		{
+			name: "invalid profile",
+			data: [][]byte{[]byte("garbage")},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := mem.NewProfileMemStorage()
+			p := NewPprofServer(store)
+			for i, data := range tc.data {
+				resp, err := p.Put(ctx, &db.PutRequest{
+					InstanceId: "a",
+					Type:       "mutex",
+					Data:       data,
+					Cumulative: tc.cumulative,
+				})
+				if status.Code(err) != tc.code {
+					t.Fatalf("expected %s, got %v", tc.code, err)
+				}
+				if err != nil {
+					return
+				}
+				if resp.Baseline != tc.baseline[i] {
+					t.Errorf("expected profile %d baseline to be %t", i, tc.baseline[i])
+				}
+			}
+			profs, err := store.Range(ctx, "a", "mutex", time.Unix(0, 0), start.Add(time.Hour))
+			if err != nil && status.Code(err) != codes.NotFound {
+				t.Fatal(err)
+			}
+			if len(profs) != tc.stored {
+				t.Fatalf("expected %d stored profiles, got %d", tc.stored, len(profs))
+			}
+			if tc.cumulative && tc.stored > 0 {
+				if got := values(profs[0]); !equalValues(got, map[string][]int64{"main.lock": {3, 300}}) {
+					t.Errorf("expected the delta since the baseline, got %v", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,587 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/alexandreLamarre/pprof-server/pkg/api/querier"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// pyroscopeQuerierPath prefixes the Connect routes of pyroscope's querier.v1.QuerierService
+	pyroscopeQuerierPath = "/querier.v1.QuerierService/"
+	// pyroscopeNameLabel lists the stored profile types
+	pyroscopeNameLabel = "__name__"
+	// pyroscopeServiceLabel is the label Grafana groups profiles by, defaults to the instance id
+	pyroscopeServiceLabel = "service_name"
+	// querier requests only hold selectors & time ranges
+	maxConnectRequestSize = 1 << 20
+)
+
+// registerPyroscopeHandlers serves the subset of pyroscope's querier API used by Grafana's Pyroscope data source
+func (p *PprofHttpServer) registerPyroscopeHandlers() {
+	p.mux.HandleFunc(pyroscopeQuerierPath+"ProfileTypes", connectUnary(p.pyroscopeProfileTypes))
+	p.mux.HandleFunc(pyroscopeQuerierPath+"LabelNames", connectUnary(p.pyroscopeLabelNames))
+	p.mux.HandleFunc(pyroscopeQuerierPath+"LabelValues", connectUnary(p.pyroscopeLabelValues))
+	p.mux.HandleFunc(pyroscopeQuerierPath+"SelectMergeStacktraces", connectUnary(p.pyroscopeSelectMergeStacktraces))
+	p.mux.HandleFunc(pyroscopeQuerierPath+"SelectSeries", connectUnary(p.pyroscopeSelectSeries))
+	p.mux.HandleFunc(pyroscopeQuerierPath, func(w http.ResponseWriter, r *http.Request) {
+		writeConnectError(w, status.Errorf(codes.Unimplemented, "%s is not implemented", strings.TrimPrefix(r.URL.Path, pyroscopeQuerierPath)))
+	})
+}
+
+type connectCodec struct {
+	marshal   func(proto.Message) ([]byte, error)
+	unmarshal func([]byte, proto.Message) error
+}
+
+// connectCodecs are the codecs of the Connect protocol's unary requests, by content type
+var connectCodecs = map[string]connectCodec{
+	"application/proto": {
+		marshal:   proto.Marshal,
+		unmarshal: proto.Unmarshal,
+	},
+	"application/json": {
+		marshal:   protojson.Marshal,
+		unmarshal: protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal,
+	},
+}
+
+// connectUnary serves a unary RPC over the Connect protocol, as spoken by Grafana's Pyroscope client
+func connectUnary[Req any, PReq interface {
+	*Req
+	proto.Message
+}, Resp proto.Message](handle func(context.Context, PReq) (Resp, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		codec, ok := connectCodecs[contentType]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported content type %q", contentType), http.StatusUnsupportedMediaType)
+			return
+		}
+		var body io.Reader = io.LimitReader(r.Body, maxConnectRequestSize+1)
+		switch encoding := r.Header.Get("Content-Encoding"); encoding {
+		case "", "identity":
+		case "gzip":
+			gz, err := gzip.NewReader(body)
+			if err != nil {
+				writeConnectError(w, status.Errorf(codes.InvalidArgument, "invalid gzip body : %s", err))
+				return
+			}
+			defer gz.Close()
+			body = io.LimitReader(gz, maxConnectRequestSize+1)
+		default:
+			writeConnectError(w, status.Errorf(codes.Unimplemented, "unsupported content encoding %s", encoding))
+			return
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			writeConnectError(w, status.Errorf(codes.InvalidArgument, "failed to read request : %s", err))
+			return
+		}
+		if len(data) > maxConnectRequestSize {
+			writeConnectError(w, status.Errorf(codes.ResourceExhausted, "request exceeds %d bytes", maxConnectRequestSize))
+			return
+		}
+		req := PReq(new(Req))
+		if err := codec.unmarshal(data, req); err != nil {
+			writeConnectError(w, status.Errorf(codes.InvalidArgument, "failed to unmarshal request : %s", err))
+			return
+		}
+		resp, err := handle(r.Context(), req)
+		if err != nil {
+			writeConnectError(w, err)
+			return
+		}
+		data, err = codec.marshal(resp)
+		if err != nil {
+			writeConnectError(w, status.Errorf(codes.Internal, "failed to marshal response : %s", err))
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(data)
+	}
+}
+
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// writeConnectError writes a gRPC status error as a Connect protocol error
+func writeConnectError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatusFromGRPC(err))
+	json.NewEncoder(w).Encode(connectError{
+		Code:    connectCode(st.Code()),
+		Message: st.Message(),
+	})
+}
+
+// connectCode returns the Connect name of a gRPC code, e.g. invalid_argument
+func connectCode(c codes.Code) string {
+	b := strings.Builder{}
+	for i, r := range c.String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pyroscopeTime converts the unix milliseconds of querier requests, zero is unbounded
+func pyroscopeTime(ms int64, unbounded time.Time) time.Time {
+	if ms == 0 {
+		return unbounded
+	}
+	return time.UnixMilli(ms)
+}
+
+// parsePyroscopeSelector parses a querier label selector. Pyroscope's reserved labels such as __profile_type__
+// are ignored, profile types are selected by the requests' profile type ids instead.
+func parsePyroscopeSelector(selector string) (storage.Selector, error) {
+	sel, err := storage.ParseSelector(selector)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	ret := storage.Selector{}
+	for _, m := range sel {
+		if !strings.HasPrefix(m.Name, "__") {
+			ret = append(ret, m)
+		}
+	}
+	return ret, nil
+}
+
+// parseProfileTypeID splits a pyroscope profile type id, <name>:<sample_type>:<sample_unit>:<period_type>:<period_unit>,
+// into the stored profile type and its sample type
+func parseProfileTypeID(id string) (string, string, error) {
+	parts := strings.Split(id, ":")
+	if len(parts) != 5 || parts[0] == "" || parts[1] == "" {
+		return "", "", status.Errorf(codes.InvalidArgument, "invalid profile type id %q, expected <name>:<sample_type>:<sample_unit>:<period_type>:<period_unit>", id)
+	}
+	return parts[0], parts[1], nil
+}
+
+// pyroscopeLabels returns the labels of an instance as seen by pyroscope queries
+func pyroscopeLabels(inst *db.Instance) map[string]string {
+	labels := make(map[string]string, len(inst.Labels)+2)
+	labels[pyroscopeServiceLabel] = inst.InstanceId
+	for k, v := range inst.Labels {
+		labels[k] = v
+	}
+	labels[storage.InstanceLabel] = inst.InstanceId
+	return labels
+}
+
+// matchingInstances returns the instances matching any of the selectors with profiles overlapping the time range,
+// every instance when there are no selectors
+func (p *PprofHttpServer) matchingInstances(ctx context.Context, selectors []storage.Selector, start, end time.Time) ([]*db.Instance, error) {
+	resp, err := p.dbClient.ListInstances(ctx, &db.ListInstancesRequest{})
+	if err != nil {
+		return nil, err
+	}
+	ret := []*db.Instance{}
+	for _, inst := range resp.GetInstances() {
+		if inst.FirstSeen.AsTime().After(end) || inst.LastSeen.AsTime().Before(start) {
+			continue
+		}
+		labels := pyroscopeLabels(inst)
+		matched := len(selectors) == 0
+		for _, sel := range selectors {
+			if sel.Matches(labels) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			ret = append(ret, inst)
+		}
+	}
+	return ret, nil
+}
+
+// parseMatchers parses the selectors of label requests
+func parseMatchers(matchers []string) ([]storage.Selector, error) {
+	ret := make([]storage.Selector, 0, len(matchers))
+	for _, m := range matchers {
+		sel, err := parsePyroscopeSelector(m)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, sel)
+	}
+	return ret, nil
+}
+
+// latestProfile is the most recent profile of a type, holding its sample types
+type latestProfile struct {
+	instanceId string
+	lastSeen   time.Time
+}
+
+func (p *PprofHttpServer) pyroscopeProfileTypes(ctx context.Context, req *querier.ProfileTypesRequest) (*querier.ProfileTypesResponse, error) {
+	start, end := pyroscopeTime(req.Start, time.Unix(0, 0)), pyroscopeTime(req.End, time.Now())
+	instances, err := p.matchingInstances(ctx, nil, start, end)
+	if err != nil {
+		return nil, err
+	}
+	latest := map[string]latestProfile{}
+	for _, inst := range instances {
+		resp, err := p.dbClient.ListProfileTypes(ctx, &db.ListProfileTypesRequest{
+			InstanceId: inst.InstanceId,
+		})
+		if status.Code(err) == codes.NotFound {
+			// evicted, compacted away or deleted since it was listed
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range resp.GetTypes() {
+			if l, ok := latest[t.Type]; !ok || t.LastSeen.AsTime().After(l.lastSeen) {
+				latest[t.Type] = latestProfile{instanceId: inst.InstanceId, lastSeen: t.LastSeen.AsTime()}
+			}
+		}
+	}
+
+	ret := &querier.ProfileTypesResponse{
+		ProfileTypes: []*querier.ProfileType{},
+	}
+	for _, name := range sortedKeys(latest) {
+		// sample types are read from the most recent profile of the type
+		l := latest[name]
+		data, err := p.getProfile(ctx, &db.GetProfileRequest{
+			InstanceId: l.instanceId,
+			Type:       name,
+			Start:      timestamppb.New(l.lastSeen),
+			End:        timestamppb.New(l.lastSeen),
+		})
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		prof, err := profile.ParseData(data)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to parse profile : %s", err)
+		}
+		ret.ProfileTypes = append(ret.ProfileTypes, pyroscopeProfileTypes(name, prof)...)
+	}
+	return ret, nil
+}
+
+// pyroscopeProfileTypes returns a pyroscope profile type for each sample type of the profile
+func pyroscopeProfileTypes(name string, prof *profile.Profile) []*querier.ProfileType {
+	var periodType, periodUnit string
+	if prof.PeriodType != nil {
+		periodType, periodUnit = prof.PeriodType.Type, prof.PeriodType.Unit
+	}
+	ret := make([]*querier.ProfileType, 0, len(prof.SampleType))
+	for _, st := range prof.SampleType {
+		ret = append(ret, &querier.ProfileType{
+			ID:         strings.Join([]string{name, st.Type, st.Unit, periodType, periodUnit}, ":"),
+			Name:       name,
+			SampleType: st.Type,
+			SampleUnit: st.Unit,
+			PeriodType: periodType,
+			PeriodUnit: periodUnit,
+		})
+	}
+	return ret
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (p *PprofHttpServer) pyroscopeLabelNames(ctx context.Context, req *querier.LabelNamesRequest) (*querier.LabelNamesResponse, error) {
+	selectors, err := parseMatchers(req.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := p.matchingInstances(ctx, selectors, pyroscopeTime(req.Start, time.Unix(0, 0)), pyroscopeTime(req.End, time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]struct{}{
+		pyroscopeNameLabel: {},
+	}
+	for _, inst := range instances {
+		for k := range pyroscopeLabels(inst) {
+			names[k] = struct{}{}
+		}
+	}
+	return &querier.LabelNamesResponse{Names: sortedKeys(names)}, nil
+}
+
+func (p *PprofHttpServer) pyroscopeLabelValues(ctx context.Context, req *querier.LabelValuesRequest) (*querier.LabelValuesResponse, error) {
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	selectors, err := parseMatchers(req.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	start, end := pyroscopeTime(req.Start, time.Unix(0, 0)), pyroscopeTime(req.End, time.Now())
+	instances, err := p.matchingInstances(ctx, selectors, start, end)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]struct{}{}
+	for _, inst := range instances {
+		if req.Name != pyroscopeNameLabel {
+			if v, ok := pyroscopeLabels(inst)[req.Name]; ok {
+				values[v] = struct{}{}
+			}
+			continue
+		}
+		resp, err := p.dbClient.ListProfileTypes(ctx, &db.ListProfileTypesRequest{
+			InstanceId: inst.InstanceId,
+		})
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range resp.GetTypes() {
+			values[t.Type] = struct{}{}
+		}
+	}
+	return &querier.LabelValuesResponse{Names: sortedKeys(values)}, nil
+}
+
+// instanceSelector selects the instances by id, pyroscope labels such as service_name aren't indexed by the store
+func instanceSelector(instances []*db.Instance) (string, error) {
+	ids := make([]string, 0, len(instances))
+	for _, inst := range instances {
+		ids = append(ids, regexp.QuoteMeta(inst.InstanceId))
+	}
+	m, err := storage.NewMatcher(storage.InstanceLabel, storage.MatchRegexp, strings.Join(ids, "|"))
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "failed to select instances : %s", err)
+	}
+	return storage.Selector{m}.String(), nil
+}
+
+// The full flame graph is returned, max_nodes is ignored
+func (p *PprofHttpServer) pyroscopeSelectMergeStacktraces(ctx context.Context, req *querier.SelectMergeStacktracesRequest) (*querier.SelectMergeStacktracesResponse, error) {
+	if req.Format == querier.ProfileFormat_PROFILE_FORMAT_TREE {
+		return nil, status.Error(codes.Unimplemented, "tree format is not implemented, use the flamegraph format")
+	}
+	profileType, sampleType, err := parseProfileTypeID(req.ProfileTypeID)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := parsePyroscopeSelector(req.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	start, end := pyroscopeTime(req.Start, time.Unix(0, 0)), pyroscopeTime(req.End, time.Now())
+	instances, err := p.matchingInstances(ctx, []storage.Selector{sel}, start, end)
+	if err != nil {
+		return nil, err
+	}
+	empty := &querier.SelectMergeStacktracesResponse{Flamegraph: &querier.FlameGraph{}}
+	if len(instances) == 0 {
+		return empty, nil
+	}
+	selector, err := instanceSelector(instances)
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.getProfile(ctx, &db.GetProfileRequest{
+		Selector: selector,
+		Type:     profileType,
+		Start:    timestamppb.New(start),
+		End:      timestamppb.New(end),
+	})
+	if status.Code(err) == codes.NotFound {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	prof, err := profile.ParseData(data)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to parse profile : %s", err)
+	}
+	idx, err := sampleIndex(prof, sampleType)
+	if err != nil {
+		return nil, err
+	}
+	return &querier.SelectMergeStacktracesResponse{
+		Flamegraph: flamebearer(flamegraph(prof, idx)),
+	}, nil
+}
+
+// flamebearer flattens the flame graph into pyroscope's levels, each level holding
+// [x offset from the end of the previous node, total, self, name index] tuples
+func flamebearer(root *flamegraphNode) *querier.FlameGraph {
+	fg := &querier.FlameGraph{
+		Names:  []string{},
+		Levels: []*querier.Level{},
+		Total:  root.Value,
+	}
+	names := map[string]int64{}
+	// end of the last node of each level
+	ends := []int64{}
+	var walk func(n *flamegraphNode, depth int, x int64, name string)
+	walk = func(n *flamegraphNode, depth int, x int64, name string) {
+		if len(fg.Levels) <= depth {
+			fg.Levels = append(fg.Levels, &querier.Level{Values: []int64{}})
+			ends = append(ends, 0)
+		}
+		idx, ok := names[name]
+		if !ok {
+			idx = int64(len(fg.Names))
+			names[name] = idx
+			fg.Names = append(fg.Names, name)
+		}
+		self := n.Value
+		for _, c := range n.Children {
+			self -= c.Value
+		}
+		if self > fg.MaxSelf {
+			fg.MaxSelf = self
+		}
+		level := fg.Levels[depth]
+		level.Values = append(level.Values, x-ends[depth], n.Value, self, idx)
+		ends[depth] = x + n.Value
+		childX := x
+		for _, c := range n.Children {
+			walk(c, depth+1, childX, c.Name)
+			childX += c.Value
+		}
+	}
+	walk(root, 0, 0, "total")
+	return fg
+}
+
+type pyroscopeSeries struct {
+	labels []*querier.LabelPair
+	// unix milliseconds -> value
+	values map[int64]float64
+	counts map[int64]int
+}
+
+// pyroscopeSelectSeries sums the stored profiles of each matching instance into step wide buckets,
+// grouping instances by the group_by labels
+func (p *PprofHttpServer) pyroscopeSelectSeries(ctx context.Context, req *querier.SelectSeriesRequest) (*querier.SelectSeriesResponse, error) {
+	profileType, sampleType, err := parseProfileTypeID(req.ProfileTypeID)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := parsePyroscopeSelector(req.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	step := defaultSeriesStep
+	if req.Step > 0 {
+		step = time.Duration(req.Step * float64(time.Second))
+	}
+	start, end := pyroscopeTime(req.Start, time.Unix(0, 0)), pyroscopeTime(req.End, time.Now())
+	instances, err := p.matchingInstances(ctx, []storage.Selector{sel}, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	series := map[string]*pyroscopeSeries{}
+	for _, inst := range instances {
+		resp, err := p.dbClient.FunctionSeries(ctx, &db.FunctionSeriesRequest{
+			InstanceId: inst.InstanceId,
+			Type:       profileType,
+			Start:      timestamppb.New(start),
+			End:        timestamppb.New(end),
+			Function:   ".*",
+			Step:       durationpb.New(step),
+			SampleType: sampleType,
+		})
+		if status.Code(err) == codes.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		labels := pyroscopeLabels(inst)
+		pairs := make([]*querier.LabelPair, 0, len(req.GroupBy))
+		keys := make([]string, 0, len(req.GroupBy))
+		for _, name := range req.GroupBy {
+			if v, ok := labels[name]; ok {
+				pairs = append(pairs, &querier.LabelPair{Name: name, Value: v})
+				keys = append(keys, name+"="+strconv.Quote(v))
+			}
+		}
+		key := strings.Join(keys, ",")
+		s, ok := series[key]
+		if !ok {
+			s = &pyroscopeSeries{labels: pairs, values: map[int64]float64{}, counts: map[int64]int{}}
+			series[key] = s
+		}
+		for _, point := range resp.Points {
+			ts := point.Timestamp.AsTime().UnixMilli()
+			s.values[ts] += float64(point.Total)
+			s.counts[ts]++
+		}
+	}
+
+	ret := &querier.SelectSeriesResponse{
+		Series: make([]*querier.Series, 0, len(series)),
+	}
+	average := req.GetAggregation() == querier.TimeSeriesAggregationType_TIME_SERIES_AGGREGATION_TYPE_AVERAGE
+	for _, key := range sortedKeys(series) {
+		s := series[key]
+		timestamps := make([]int64, 0, len(s.values))
+		for ts := range s.values {
+			timestamps = append(timestamps, ts)
+		}
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+		points := make([]*querier.Point, 0, len(timestamps))
+		for _, ts := range timestamps {
+			v := s.values[ts]
+			if average {
+				v /= float64(s.counts[ts])
+			}
+			points = append(points, &querier.Point{Value: v, Timestamp: ts})
+		}
+		ret.Series = append(ret.Series, &querier.Series{Labels: s.labels, Points: points})
+	}
+	return ret, nil
+}
@@ -0,0 +1,344 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/alexandreLamarre/pprof-server/pkg/api/querier"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	"github.com/google/pprof/profile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestHttpServer serves the store over an in memory gRPC connection, like the HTTP server in main
+func newTestHttpServer(t *testing.T, store storage.ProfileStore) *PprofHttpServer {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	db.RegisterDBServer(grpcServer, NewPprofServer(store))
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	p := NewHttpServer("", db.NewDBClient(conn), nil)
+	// handlers are registered on the default mux when serving
+	p.mux = http.NewServeMux()
+	p.registerHandlers()
+	return p
+}
+
+// callQuerier calls a querier RPC over the Connect protocol, returning the HTTP status
+func callQuerier(t *testing.T, p *PprofHttpServer, method string, req, resp proto.Message) int {
+	t.Helper()
+	data, err := protojson.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, pyroscopeQuerierPath+method, bytes.NewReader(data))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	p.mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		return w.Code
+	}
+	if err := protojson.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code
+}
+
+func cpuProfile(at time.Time, values map[string]int64) *profile.Profile {
+	prof := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		PeriodType:    &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:        10000000,
+		TimeNanos:     at.UnixNano(),
+		DurationNanos: (10 * time.Second).Nanoseconds(),
+	}
+	for _, name := range sortedKeys(values) {
+		fn := &profile.Function{ID: uint64(len(prof.Function) + 1), Name: name}
+		loc := &profile.Location{ID: fn.ID, Line: []profile.Line{{Function: fn}}}
+		prof.Function = append(prof.Function, fn)
+		prof.Location = append(prof.Location, loc)
+		prof.Sample = append(prof.Sample, &profile.Sample{
+			Location: []*profile.Location{loc},
+			Value:    []int64{values[name], values[name] * 10000000},
+		})
+	}
+	return prof
+}
+
+func TestPyroscopeQuerier(t *testing.T) {
+	ctx := context.Background()
+	store := mem.NewProfileMemStorage()
+	now := time.Now().Truncate(time.Minute)
+	for id, labels := range map[string]map[string]string{
+		"api-1": {"service_name": "api", "region": "eu"},
+		"api-2": {"service_name": "api", "region": "us"},
+		"db-1":  {"region": "eu"},
+	} {
+		for i := range 3 {
+			prof := cpuProfile(now.Add(-time.Duration(i)*time.Minute), map[string]int64{"main.work": 2, "main.wait": 1})
+			if err := store.Put(ctx, id, "cpu", labels, []*profile.Profile{prof}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	p := newTestHttpServer(t, store)
+	start, end := now.Add(-time.Hour).UnixMilli(), now.Add(time.Minute).UnixMilli()
+
+	types := &querier.ProfileTypesResponse{}
+	if code := callQuerier(t, p, "ProfileTypes", &querier.ProfileTypesRequest{Start: start, End: end}, types); code != http.StatusOK {
+		t.Fatalf("ProfileTypes returned %d", code)
+	}
+	ids := []string{}
+	for _, pt := range types.ProfileTypes {
+		ids = append(ids, pt.ID)
+	}
+	if expected := []string{"cpu:samples:count:cpu:nanoseconds", "cpu:cpu:nanoseconds:cpu:nanoseconds"}; !slices.Equal(ids, expected) {
+		t.Errorf("expected profile types %v, got %v", expected, ids)
+	}
+
+	values := &querier.LabelValuesResponse{}
+	if code := callQuerier(t, p, "LabelValues", &querier.LabelValuesRequest{Name: "service_name", Matchers: []string{`{region="eu"}`}}, values); code != http.StatusOK {
+		t.Fatalf("LabelValues returned %d", code)
+	}
+	// instances without a service_name label are named after their id
+	if expected := []string{"api", "db-1"}; !slices.Equal(values.Names, expected) {
+		t.Errorf("expected label values %v, got %v", expected, values.Names)
+	}
+
+	names := &querier.LabelNamesResponse{}
+	if code := callQuerier(t, p, "LabelNames", &querier.LabelNamesRequest{}, names); code != http.StatusOK {
+		t.Fatalf("LabelNames returned %d", code)
+	}
+	if expected := []string{"__name__", "instance", "region", "service_name"}; !slices.Equal(names.Names, expected) {
+		t.Errorf("expected label names %v, got %v", expected, names.Names)
+	}
+
+	stacks := &querier.SelectMergeStacktracesResponse{}
+	code := callQuerier(t, p, "SelectMergeStacktraces", &querier.SelectMergeStacktracesRequest{
+		ProfileTypeID: "cpu:samples:count:cpu:nanoseconds",
+		LabelSelector: `{service_name="api", __profile_type__="cpu:samples:count:cpu:nanoseconds"}`,
+		Start:         start,
+		End:           end,
+	}, stacks)
+	if code != http.StatusOK {
+		t.Fatalf("SelectMergeStacktraces returned %d", code)
+	}
+	// 2 instances * 3 profiles * 3 samples
+	if stacks.Flamegraph.Total != 18 {
+		t.Errorf("expected a total of 18 samples, got %d", stacks.Flamegraph.Total)
+	}
+
+	series := &querier.SelectSeriesResponse{}
+	code = callQuerier(t, p, "SelectSeries", &querier.SelectSeriesRequest{
+		ProfileTypeID: "cpu:samples:count:cpu:nanoseconds",
+		LabelSelector: `{region="eu"}`,
+		Start:         start,
+		End:           end,
+		GroupBy:       []string{"service_name"},
+		Step:          60,
+	}, series)
+	if code != http.StatusOK {
+		t.Fatalf("SelectSeries returned %d", code)
+	}
+	if len(series.Series) != 2 {
+		t.Fatalf("expected a series per service, got %d", len(series.Series))
+	}
+	for _, s := range series.Series {
+		if len(s.Points) != 3 {
+			t.Errorf("expected a point per minute for %v, got %d", s.Labels, len(s.Points))
+		}
+		for _, point := range s.Points {
+			if point.Value != 3 {
+				t.Errorf("expected 3 samples per minute for %v, got %f", s.Labels, point.Value)
+			}
+		}
+	}
+
+	if code := callQuerier(t, p, "SelectMergeStacktraces", &querier.SelectMergeStacktracesRequest{ProfileTypeID: "cpu"}, stacks); code != http.StatusBadRequest {
+		t.Errorf("expected invalid profile type ids to be rejected, got %d", code)
+	}
+}
+
+func TestConnectUnary(t *testing.T) {
+	p := newTestHttpServer(t, mem.NewProfileMemStorage())
+	testCases := []struct {
+		name        string
+		path        string
+		contentType string
+		body        []byte
+		code        int
+		errCode     string
+	}{
+		{
+			name:        "proto",
+			path:        pyroscopeQuerierPath + "LabelNames",
+			contentType: "application/proto",
+			body:        mustMarshal(t, &querier.LabelNamesRequest{}),
+			code:        http.StatusOK,
+		},
+		{
+			name:        "invalid argument",
+			path:        pyroscopeQuerierPath + "LabelValues",
+			contentType: "application/json",
+			body:        []byte(`{}`),
+			code:        http.StatusBadRequest,
+			errCode:     "invalid_argument",
+		},
+		{
+			name:        "unimplemented",
+			path:        pyroscopeQuerierPath + "Diff",
+			contentType: "application/json",
+			body:        []byte(`{}`),
+			code:        http.StatusNotImplemented,
+			errCode:     "unimplemented",
+		},
+		{
+			name:        "unsupported content type",
+			path:        pyroscopeQuerierPath + "LabelNames",
+			contentType: "text/plain",
+			code:        http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+			p.mux.ServeHTTP(w, r)
+			if w.Code != tc.code {
+				t.Fatalf("expected %d, got %d : %s", tc.code, w.Code, w.Body)
+			}
+			if tc.errCode == "" {
+				return
+			}
+			var connectErr connectError
+			if err := json.Unmarshal(w.Body.Bytes(), &connectErr); err != nil {
+				t.Fatal(err)
+			}
+			if connectErr.Code != tc.errCode {
+				t.Errorf("expected error code %s, got %s", tc.errCode, connectErr.Code)
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, msg proto.Message) []byte {
+	t.Helper()
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestConnectCode(t *testing.T) {
+	for c, expected := range map[codes.Code]string{
+		codes.Canceled:           "canceled",
+		codes.InvalidArgument:    "invalid_argument",
+		codes.NotFound:           "not_found",
+		codes.ResourceExhausted:  "resource_exhausted",
+		codes.FailedPrecondition: "failed_precondition",
+		codes.DataLoss:           "data_loss",
+	} {
+		if got := connectCode(c); got != expected {
+			t.Errorf("expected %s, got %s", expected, got)
+		}
+	}
+}
+
+func TestFlamebearer(t *testing.T) {
+	node := func(name string, value int64, children ...*flamegraphNode) *flamegraphNode {
+		return &flamegraphNode{Name: name, Value: value, Children: children}
+	}
+	testCases := []struct {
+		name    string
+		root    *flamegraphNode
+		names   []string
+		levels  [][]int64
+		maxSelf int64
+	}{
+		{
+			name:   "empty",
+			root:   node("root", 0),
+			names:  []string{"total"},
+			levels: [][]int64{{0, 0, 0, 0}},
+		},
+		{
+			name: "siblings",
+			root: node("root", 10,
+				node("main.a", 6, node("main.c", 4)),
+				node("main.b", 4),
+			),
+			names: []string{"total", "main.a", "main.c", "main.b"},
+			levels: [][]int64{
+				{0, 10, 0, 0},
+				{0, 6, 2, 1, 0, 4, 4, 3},
+				{0, 4, 4, 2},
+			},
+			maxSelf: 4,
+		},
+		{
+			// offsets are relative to the end of the previous node of the level
+			name: "gaps",
+			root: node("root", 10,
+				node("main.a", 4),
+				node("main.b", 6, node("main.a", 1), node("main.d", 3)),
+			),
+			names: []string{"total", "main.a", "main.b", "main.d"},
+			levels: [][]int64{
+				{0, 10, 0, 0},
+				{0, 4, 4, 1, 0, 6, 2, 2},
+				{4, 1, 1, 1, 0, 3, 3, 3},
+			},
+			maxSelf: 4,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fg := flamebearer(tc.root)
+			if !slices.Equal(fg.Names, tc.names) {
+				t.Errorf("expected names %v, got %v", tc.names, fg.Names)
+			}
+			if fg.Total != tc.root.Value || fg.MaxSelf != tc.maxSelf {
+				t.Errorf("expected total %d & max self %d, got %d & %d", tc.root.Value, tc.maxSelf, fg.Total, fg.MaxSelf)
+			}
+			if len(fg.Levels) != len(tc.levels) {
+				t.Fatalf("expected %d levels, got %d", len(tc.levels), len(fg.Levels))
+			}
+			for i, level := range fg.Levels {
+				if !slices.Equal(level.Values, tc.levels[i]) {
+					t.Errorf("expected level %d to be %v, got %v", i, tc.levels[i], level.Values)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/google/pprof/profile"
+	"github.com/samber/lo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultSeriesStep = time.Minute
+
+func (p *PprofServer) FunctionSeries(ctx context.Context, req *db.FunctionSeriesRequest) (*db.FunctionSeriesResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	startTime := lo.ToPtr(lo.FromPtrOr(req.Start, *timestamppb.New(time.Unix(0, 0)))).AsTime()
+	endTime := lo.ToPtr(lo.FromPtrOr(req.End, *timestamppb.New(time.Now()))).AsTime()
+	profs, err := p.store.Range(ctx, req.InstanceId, req.Type, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	if len(profs) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no %s profiles for %s in time range", req.Type, req.InstanceId)
+	}
+	step := defaultSeriesStep
+	if req.Step != nil {
+		step = req.Step.AsDuration()
+	}
+	idx, err := sampleIndex(profs[0], req.SampleType)
+	if err != nil {
+		return nil, err
+	}
+	sampleType := profs[0].SampleType[idx]
+	re := regexp.MustCompile(req.Function)
+
+	resp := &db.FunctionSeriesResponse{
+		Points:     []*db.FunctionSeriesPoint{},
+		SampleType: sampleType.Type,
+		Unit:       sampleType.Unit,
+	}
+	var point *db.FunctionSeriesPoint
+	for _, prof := range profs {
+		idx, err := sampleIndex(prof, sampleType.Type)
+		if err != nil {
+			return nil, err
+		}
+		// buckets are aligned on the step so the series of different queries line up,
+		// profiles are sorted by start time so buckets are filled in order
+		bucket := time.Unix(0, prof.TimeNanos).Truncate(step)
+		if point == nil || !point.Timestamp.AsTime().Equal(bucket) {
+			point = &db.FunctionSeriesPoint{
+				Timestamp: timestamppb.New(bucket),
+			}
+			resp.Points = append(resp.Points, point)
+		}
+		addFunctionCost(point, prof, idx, re)
+	}
+	return resp, nil
+}
+
+// addFunctionCost adds the flat & cumulative values of the functions matching re to the point
+func addFunctionCost(point *db.FunctionSeriesPoint, prof *profile.Profile, idx int, re *regexp.Regexp) {
+	for _, s := range prof.Sample {
+		v := s.Value[idx]
+		if v == 0 {
+			continue
+		}
+		point.Total += v
+		frames := stack(s)
+		if len(frames) == 0 {
+			continue
+		}
+		if re.MatchString(frames[len(frames)-1].Name) {
+			point.Flat += v
+		}
+		for _, f := range frames {
+			if re.MatchString(f.Name) {
+				point.Cum += v
+				break
+			}
+		}
+	}
+}
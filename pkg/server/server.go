@@ -1,9 +1,11 @@
 package server
 
 import (
+	"context"
+
 	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
 	"github.com/alexandreLamarre/pprof-server/pkg/storage"
-	"github.com/alexandreLamarre/pprof-server/pkg/storage/driver/mem"
+	"github.com/google/pprof/profile"
 	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 )
 
@@ -12,11 +14,45 @@ type PprofServer struct {
 	db.UnsafeDBServer
 
 	// id -> storedProfiles
-	store storage.ProfileStore
+	store  storage.ProfileStore
+	deltas *deltaTracker
 }
 
-func NewPprofServer() *PprofServer {
+func NewPprofServer(store storage.ProfileStore) *PprofServer {
 	return &PprofServer{
-		store: mem.NewProfileMemStorage(),
+		store:  store,
+		deltas: newDeltaTracker(),
+	}
+}
+
+// Ingest stores a profile, converting cumulative profiles into deltas since the previous profile.
+// The first cumulative profile of an instance only serves as the baseline of the next delta.
+func (p *PprofServer) Ingest(
+	ctx context.Context,
+	instanceId, profileType string,
+	metadata map[string]string,
+	prof *profile.Profile,
+) error {
+	_, err := p.ingestCumulative(ctx, instanceId, profileType, metadata, prof)
+	return err
+}
+
+// ingestCumulative is Ingest, reporting whether the cumulative values of the profile only served as a baseline
+func (p *PprofServer) ingestCumulative(
+	ctx context.Context,
+	instanceId, profileType string,
+	metadata map[string]string,
+	prof *profile.Profile,
+) (bool, error) {
+	prof, isBaseline, commit := p.deltas.delta(instanceId, profileType, prof)
+	if prof == nil {
+		// baseline of a cumulative profile, there is nothing to store until the next profile
+		commit()
+		return true, nil
+	}
+	if err := p.store.Put(ctx, instanceId, profileType, metadata, []*profile.Profile{prof}); err != nil {
+		return false, err
 	}
+	commit()
+	return isBaseline, nil
 }
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"sort"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/api/db"
+	"github.com/google/pprof/profile"
+)
+
+const defaultTopLimit = 20
+
+func (p *PprofServer) Top(ctx context.Context, req *db.TopRequest) (*db.TopResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	prof, err := p.query(ctx, req.InstanceId, req.Selector, req.Type, req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := sampleIndex(prof, req.SampleType)
+	if err != nil {
+		return nil, err
+	}
+	filterSamples(prof, req.Focus, req.Ignore)
+
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = defaultTopLimit
+	}
+	rows, total := topFunctions(prof, idx)
+	sort.SliceStable(rows, func(i, j int) bool {
+		if req.SortByCum && rows[i].Cum != rows[j].Cum {
+			return rows[i].Cum > rows[j].Cum
+		}
+		if rows[i].Flat != rows[j].Flat {
+			return rows[i].Flat > rows[j].Flat
+		}
+		return rows[i].Function < rows[j].Function
+	})
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return &db.TopResponse{
+		Rows:       rows,
+		Total:      total,
+		SampleType: prof.SampleType[idx].Type,
+		Unit:       prof.SampleType[idx].Unit,
+	}, nil
+}
+
+// filterSamples keeps the samples with a frame matching focus and drops the ones with a frame matching ignore,
+// like `pprof -focus -ignore`. The regexps are validated with the request.
+func filterSamples(prof *profile.Profile, focus, ignore string) {
+	var focusRe, ignoreRe *regexp.Regexp
+	if focus != "" {
+		focusRe = regexp.MustCompile(focus)
+	}
+	if ignore != "" {
+		ignoreRe = regexp.MustCompile(ignore)
+	}
+	prof.FilterSamplesByName(focusRe, ignoreRe, nil, nil)
+}
+
+// topFunctions aggregates the flat & cumulative values of each function of the profile
+func topFunctions(prof *profile.Profile, idx int) ([]*db.TopRow, int64) {
+	type key struct {
+		name, file string
+	}
+	rows := map[key]*db.TopRow{}
+	row := func(f frame) *db.TopRow {
+		k := key{f.Name, f.File}
+		r, ok := rows[k]
+		if !ok {
+			r = &db.TopRow{
+				Function: f.Name,
+				File:     f.File,
+			}
+			rows[k] = r
+		}
+		return r
+	}
+
+	var total int64
+	for _, s := range prof.Sample {
+		v := s.Value[idx]
+		if v == 0 {
+			continue
+		}
+		total += v
+		frames := stack(s)
+		if len(frames) == 0 {
+			continue
+		}
+		row(frames[len(frames)-1]).Flat += v
+		// recursive functions only count once towards the cumulative value
+		seen := map[frame]struct{}{}
+		for _, f := range frames {
+			if _, ok := seen[f]; ok {
+				continue
+			}
+			seen[f] = struct{}{}
+			row(f).Cum += v
+		}
+	}
+
+	ret := make([]*db.TopRow, 0, len(rows))
+	for _, r := range rows {
+		if total != 0 {
+			r.FlatPercent = 100 * float64(r.Flat) / float64(total)
+			r.CumPercent = 100 * float64(r.Cum) / float64(total)
+		}
+		ret = append(ret, r)
+	}
+	return ret, total
+}
@@ -0,0 +1,262 @@
+package disk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/google/pprof/profile"
+	"github.com/sirupsen/logrus"
+)
+
+// Compact deletes profiles past their retention and merges older profiles into coarser buckets
+func (d *ProfileDiskStorage) Compact(ctx context.Context, now time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.flushLocked(); err != nil {
+		return err
+	}
+
+	removed := map[*entry]struct{}{}
+	added := []*entry{}
+	buf := &bytes.Buffer{}
+	for id, types := range d.index {
+		for pType, entries := range types {
+			expired, groups := storage.PlanCompaction(d.opts.Retention.For(pType), now, entries, func(e *entry) (time.Time, time.Time) {
+				return time.Unix(0, e.hdr.Start), time.Unix(0, e.hdr.End)
+			})
+			for _, e := range expired {
+				removed[e] = struct{}{}
+			}
+			for _, group := range groups {
+				profs := make([]*profile.Profile, 0, len(group))
+				replaces := make([]uint64, 0, len(group))
+				for _, e := range group {
+					prof, err := e.load()
+					if err != nil {
+						return err
+					}
+					profs = append(profs, prof)
+					replaces = append(replaces, e.seq)
+				}
+				merged, err := profile.Merge(profs)
+				if err != nil {
+					logrus.Warnf("skipping compaction of %s profiles for %s : %s", pType, id, err)
+					continue
+				}
+				buf.Reset()
+				if err := merged.Write(buf); err != nil {
+					return err
+				}
+				// the merged profile keeps the summed durations, the header tracks the time range it covers
+				start, end := storage.Span(profs)
+				hdr := recordHeader{
+					InstanceId:  id,
+					ProfileType: pType,
+					Labels:      group[len(group)-1].hdr.Labels,
+					Start:       start.UnixNano(),
+					End:         end.UnixNano(),
+					Replaces:    replaces,
+				}
+				seq := d.nextSeq
+				d.nextSeq++
+				payload, err := encodeSequenced(seq, hdr, buf.Bytes())
+				if err != nil {
+					return err
+				}
+				added = append(added, &entry{
+					hdr:     hdr,
+					seq:     seq,
+					payload: payload,
+				})
+				for _, e := range group {
+					removed[e] = struct{}{}
+				}
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if err := d.rewriteLocked(removed, added); err != nil {
+		return err
+	}
+	logrus.Debugf("compaction removed %d profiles, added %d compacted profiles", len(removed), len(added))
+	return nil
+}
+
+// rewriteLocked removes the entries from the store and adds the new ones, assumes the lock is held
+// and the write-ahead log flushed.
+//
+// Segments are immutable, so every segment holding a removed entry is rewritten with its remaining entries,
+// then deleted.
+func (d *ProfileDiskStorage) rewriteLocked(removed map[*entry]struct{}, added []*entry) error {
+	affected := map[string]struct{}{}
+	for e := range removed {
+		affected[e.segment] = struct{}{}
+	}
+	rewrite := []*entry{}
+	for _, types := range d.index {
+		for _, entries := range types {
+			for _, e := range entries {
+				if _, ok := removed[e]; ok {
+					continue
+				}
+				if _, ok := affected[e.segment]; !ok {
+					continue
+				}
+				payload, err := readFrameAt(e.segment, e.offset)
+				if err != nil {
+					return err
+				}
+				e.payload = payload
+				rewrite = append(rewrite, e)
+			}
+		}
+	}
+
+	// tombstones are rewritten with their segment as long as the segments they removed records from
+	// are left on disk, otherwise reloading the store would bring the removed records back
+	d.removeLeftovers()
+	tombstones := make([]*entry, 0, len(d.tombstones)+1)
+	for _, t := range d.tombstones {
+		if _, ok := affected[t.segment]; ok {
+			if !d.hasLeftovers(t) {
+				continue
+			}
+			payload, err := readFrameAt(t.segment, t.offset)
+			if err != nil {
+				return err
+			}
+			t.payload = payload
+			rewrite = append(rewrite, t)
+		}
+		tombstones = append(tombstones, t)
+	}
+
+	tombstone, err := d.tombstone(removed, affected)
+	if err != nil {
+		return err
+	}
+
+	d.untrack(removed)
+	for _, e := range added {
+		d.track(e)
+	}
+	d.tombstones = append(tombstones, tombstone)
+	// the tombstone is written with the rewritten segments, before the old segments are deleted,
+	// so a crash in between doesn't bring removed profiles back
+	d.head = append(append(rewrite, added...), tombstone)
+	if err := d.flushLocked(); err != nil {
+		return err
+	}
+	var errs []error
+	for path := range affected {
+		if err := os.Remove(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tombstone creates the record marking the entries as removed from the affected segments, assumes the lock is held.
+func (d *ProfileDiskStorage) tombstone(removed map[*entry]struct{}, affected map[string]struct{}) (*entry, error) {
+	hdr := recordHeader{
+		Tombstone: true,
+		Replaces:  make([]uint64, 0, len(removed)),
+		Segments:  make([]string, 0, len(affected)),
+	}
+	for path := range affected {
+		hdr.Segments = append(hdr.Segments, filepath.Base(path))
+	}
+	sort.Strings(hdr.Segments)
+	for e := range removed {
+		hdr.Replaces = append(hdr.Replaces, e.seq)
+		if hdr.Start == 0 || e.hdr.Start < hdr.Start {
+			hdr.Start = e.hdr.Start
+		}
+		if e.hdr.End > hdr.End {
+			hdr.End = e.hdr.End
+		}
+	}
+	sort.Slice(hdr.Replaces, func(i, j int) bool { return hdr.Replaces[i] < hdr.Replaces[j] })
+	seq := d.nextSeq
+	d.nextSeq++
+	payload, err := encodeSequenced(seq, hdr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &entry{
+		hdr:     hdr,
+		seq:     seq,
+		payload: payload,
+	}, nil
+}
+
+// hasLeftovers reports whether segments the tombstone removed records from are still on disk
+func (d *ProfileDiskStorage) hasLeftovers(t *entry) bool {
+	for _, name := range t.hdr.Segments {
+		if _, err := os.Stat(filepath.Join(d.dir, segmentDir, name)); !errors.Is(err, fs.ErrNotExist) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLeftovers retries deleting the segments tombstones removed records from, assumes the lock is held.
+// Segments still holding tracked records, e.g. when crashing before their records were rewritten, are kept.
+func (d *ProfileDiskStorage) removeLeftovers() {
+	inUse := map[string]struct{}{}
+	for _, types := range d.index {
+		for _, entries := range types {
+			for _, e := range entries {
+				inUse[e.segment] = struct{}{}
+			}
+		}
+	}
+	for _, t := range d.tombstones {
+		inUse[t.segment] = struct{}{}
+	}
+	for _, t := range d.tombstones {
+		for _, name := range t.hdr.Segments {
+			path := filepath.Join(d.dir, segmentDir, name)
+			if _, ok := inUse[path]; ok {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				logrus.Warnf("failed to remove segment %s : %s", name, err)
+			}
+		}
+	}
+}
+
+// untrack removes the entries from the in-memory index, assumes the lock is held
+func (d *ProfileDiskStorage) untrack(removed map[*entry]struct{}) {
+	for id, types := range d.index {
+		for pType, entries := range types {
+			kept := make([]*entry, 0, len(entries))
+			for _, e := range entries {
+				if _, ok := removed[e]; !ok {
+					kept = append(kept, e)
+				}
+			}
+			if len(kept) == 0 {
+				delete(types, pType)
+				continue
+			}
+			types[pType] = kept
+		}
+		if len(types) == 0 {
+			delete(d.index, id)
+			d.labelIndex.Delete(id)
+			delete(d.labelSeq, id)
+		}
+	}
+}
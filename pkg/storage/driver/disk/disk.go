@@ -0,0 +1,749 @@
+package disk
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/google/pprof/profile"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	walDir      = "wal"
+	walFile     = "wal.log"
+	segmentDir  = "segments"
+	segmentExt  = ".seg"
+	tmpExt      = ".tmp"
+	defaultPerm = 0o755
+)
+
+type Options struct {
+	// FlushInterval is how often profiles in the write-ahead log are flushed into segment files.
+	// A value of 0 disables background flushing.
+	FlushInterval time.Duration
+	// PartitionDuration is the time range covered by a single segment file
+	PartitionDuration time.Duration
+	// MaxWALSize forces a flush once the write-ahead log grows past this many bytes
+	MaxWALSize int64
+	// Retention is applied to stored profiles when compacting
+	Retention storage.RetentionPolicies
+}
+
+func DefaultOptions() Options {
+	return Options{
+		FlushInterval:     time.Minute,
+		PartitionDuration: time.Hour,
+		MaxWALSize:        64 * 1024 * 1024,
+		Retention:         storage.DefaultRetentionPolicies(),
+	}
+}
+
+// entry tracks where a stored profile lives : in the write-ahead log
+// (payload is set) or in an immutable segment file.
+type entry struct {
+	hdr recordHeader
+	seq uint64
+
+	payload []byte
+
+	segment string
+	offset  int64
+}
+
+type ProfileDiskStorage struct {
+	dir  string
+	opts Options
+
+	mu      sync.RWMutex
+	wal     *os.File
+	walSize int64
+	nextSeq uint64
+	// entries that are only persisted in the write-ahead log
+	head []*entry
+	// tombstones stored in segments, they aren't indexed
+	tombstones []*entry
+	// id -> profile type -> entries
+	index      map[string]map[string][]*entry
+	labelIndex *storage.LabelIndex
+	// id -> sequence number the labels were last updated at
+	labelSeq map[string]uint64
+
+	stopC chan struct{}
+	doneC chan struct{}
+}
+
+var _ storage.ProfileStore = (*ProfileDiskStorage)(nil)
+var _ storage.Compactor = (*ProfileDiskStorage)(nil)
+
+// NewProfileDiskStorage opens (or creates) a profile store rooted at dir,
+// replaying any profiles left in the write-ahead log by a previous run.
+func NewProfileDiskStorage(dir string, opts Options) (*ProfileDiskStorage, error) {
+	if opts.PartitionDuration <= 0 {
+		return nil, fmt.Errorf("partition duration must be positive")
+	}
+	for _, sub := range []string{walDir, segmentDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), defaultPerm); err != nil {
+			return nil, err
+		}
+	}
+	d := &ProfileDiskStorage{
+		dir:        dir,
+		opts:       opts,
+		index:      map[string]map[string][]*entry{},
+		labelIndex: storage.NewLabelIndex(),
+		labelSeq:   map[string]uint64{},
+		stopC:      make(chan struct{}),
+		doneC:      make(chan struct{}),
+	}
+	flushed, err := d.loadSegments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load segments : %w", err)
+	}
+	if err := d.replayWAL(flushed); err != nil {
+		return nil, fmt.Errorf("failed to replay write-ahead log : %w", err)
+	}
+	go d.run()
+	return d, nil
+}
+
+func (d *ProfileDiskStorage) run() {
+	defer close(d.doneC)
+	if d.opts.FlushInterval <= 0 {
+		<-d.stopC
+		return
+	}
+	t := time.NewTicker(d.opts.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-d.stopC:
+			return
+		case <-t.C:
+			if err := d.Flush(); err != nil {
+				logrus.Errorf("failed to flush write-ahead log : %s", err)
+			}
+		}
+	}
+}
+
+// Close flushes the write-ahead log and releases the underlying files.
+func (d *ProfileDiskStorage) Close() error {
+	close(d.stopC)
+	<-d.doneC
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	flushErr := d.flushLocked()
+	return errors.Join(flushErr, d.wal.Close())
+}
+
+// loadSegments indexes every segment on disk, returning the sequence numbers they contain
+func (d *ProfileDiskStorage) loadSegments() (map[uint64]struct{}, error) {
+	flushed := map[uint64]struct{}{}
+	entries := []*entry{}
+	files, err := os.ReadDir(filepath.Join(d.dir, segmentDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		path := filepath.Join(d.dir, segmentDir, f.Name())
+		if strings.HasSuffix(f.Name(), tmpExt) {
+			// incomplete flush, the data is still in the write-ahead log
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !strings.HasSuffix(f.Name(), segmentExt) {
+			continue
+		}
+		if seq, ok := segmentSeq(f.Name()); ok && seq >= d.nextSeq {
+			d.nextSeq = seq + 1
+		}
+		segEntries, err := loadSegment(path)
+		if err != nil {
+			return nil, fmt.Errorf("segment %s : %w", f.Name(), err)
+		}
+		entries = append(entries, segEntries...)
+	}
+
+	// the segments tombstones removed records from are left on disk when crashing before deleting them,
+	// their records are kept in the rewritten segments so those copies take precedence
+	leftover := map[string]struct{}{}
+	for _, e := range entries {
+		for _, name := range e.hdr.Segments {
+			leftover[filepath.Join(d.dir, segmentDir, name)] = struct{}{}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		_, li := leftover[entries[i].segment]
+		_, lj := leftover[entries[j].segment]
+		return !li && lj
+	})
+
+	// a crash during compaction can leave both the compacted records and the records they replace on disk
+	for _, e := range entries {
+		for _, seq := range e.hdr.Replaces {
+			flushed[seq] = struct{}{}
+		}
+	}
+	for _, e := range entries {
+		if _, ok := flushed[e.seq]; ok {
+			continue
+		}
+		flushed[e.seq] = struct{}{}
+		if e.hdr.Tombstone {
+			d.tombstones = append(d.tombstones, e)
+			continue
+		}
+		d.track(e)
+	}
+	d.removeLeftovers()
+	return flushed, nil
+}
+
+// segment files are named <partition start>-<sequence number>.seg
+func segmentSeq(name string) (uint64, bool) {
+	_, seq, ok := strings.Cut(strings.TrimSuffix(name, segmentExt), "-")
+	if !ok {
+		return 0, false
+	}
+	ret, err := strconv.ParseUint(seq, 10, 64)
+	return ret, err == nil
+}
+
+func loadSegment(path string) ([]*entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	entries := []*entry{}
+	r := newFrameReader(f, info.Size())
+	for {
+		offset, payload, err := r.next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		seq, hdr, _, err := decodeSequenced(payload)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry{
+			hdr:     hdr,
+			seq:     seq,
+			segment: path,
+			offset:  offset,
+		})
+	}
+}
+
+func (d *ProfileDiskStorage) replayWAL(flushed map[uint64]struct{}) error {
+	path := filepath.Join(d.dir, walDir, walFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r := newFrameReader(f, info.Size())
+	replayed := 0
+	for {
+		_, payload, err := r.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, errCorruptFrame) {
+			logrus.Warnf("truncating torn write-ahead log write at offset %d", r.offset)
+			break
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+		seq, hdr, _, err := decodeSequenced(payload)
+		if err != nil {
+			logrus.Warnf("truncating corrupt write-ahead log record at offset %d", r.offset)
+			break
+		}
+		if seq >= d.nextSeq {
+			d.nextSeq = seq + 1
+		}
+		if _, ok := flushed[seq]; ok {
+			// crashed after flushing segments but before truncating the log
+			continue
+		}
+		e := &entry{
+			hdr:     hdr,
+			seq:     seq,
+			payload: payload,
+		}
+		d.head = append(d.head, e)
+		d.track(e)
+		replayed++
+	}
+	if err := f.Truncate(r.offset); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Seek(r.offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	d.wal = f
+	d.walSize = r.offset
+	if replayed > 0 {
+		logrus.Infof("replayed %d profiles from write-ahead log", replayed)
+	}
+	return nil
+}
+
+// track adds the entry to the in-memory index, assumes the lock is held
+func (d *ProfileDiskStorage) track(e *entry) {
+	if seq := e.seq + 1; seq > d.nextSeq {
+		d.nextSeq = seq
+	}
+	if _, ok := d.index[e.hdr.InstanceId]; !ok {
+		d.index[e.hdr.InstanceId] = map[string][]*entry{}
+	}
+	d.index[e.hdr.InstanceId][e.hdr.ProfileType] = append(d.index[e.hdr.InstanceId][e.hdr.ProfileType], e)
+	// TODO : labels should be segmented per profile
+	if _, ok := d.labelSeq[e.hdr.InstanceId]; !ok || e.seq >= d.labelSeq[e.hdr.InstanceId] {
+		d.labelIndex.Set(e.hdr.InstanceId, e.hdr.Labels)
+		d.labelSeq[e.hdr.InstanceId] = e.seq
+	}
+}
+
+// payloads are prefixed with the record's sequence number, used to de-duplicate replays
+func encodeSequenced(seq uint64, hdr recordHeader, data []byte) ([]byte, error) {
+	record, err := encodeRecord(hdr, data)
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.AppendUvarint(nil, seq), record...), nil
+}
+
+func decodeSequenced(payload []byte) (uint64, recordHeader, []byte, error) {
+	seq, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return 0, recordHeader{}, nil, errCorruptFrame
+	}
+	hdr, data, err := decodeRecord(payload[n:])
+	return seq, hdr, data, err
+}
+
+func (d *ProfileDiskStorage) Put(
+	ctx context.Context,
+	instanceId, profileType string,
+	metadata map[string]string,
+	profs []*profile.Profile,
+) error {
+	metadata = storage.ExportLabels(metadata)
+	entries := make([]*entry, 0, len(profs))
+	buf := &bytes.Buffer{}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// profiles of a failed Put are removed from the log, so they aren't replayed and
+	// a torn write doesn't hide the profiles appended after it
+	offset := d.walSize
+	for _, prof := range profs {
+		buf.Reset()
+		if err := prof.Write(buf); err != nil {
+			return d.rollbackWAL(offset, status.Errorf(codes.Internal, "failed to encode profile : %s", err))
+		}
+		start, end := rangeFromProfile(prof)
+		hdr := recordHeader{
+			InstanceId:  instanceId,
+			ProfileType: profileType,
+			Labels:      metadata,
+			Start:       start.UnixNano(),
+			End:         end.UnixNano(),
+		}
+		seq := d.nextSeq
+		payload, err := encodeSequenced(seq, hdr, buf.Bytes())
+		if err != nil {
+			return d.rollbackWAL(offset, status.Errorf(codes.Internal, "failed to encode profile : %s", err))
+		}
+		n, err := writeFrame(d.wal, payload)
+		d.walSize += int64(n)
+		if err != nil {
+			return d.rollbackWAL(offset, status.Errorf(codes.Unavailable, "failed to append to write-ahead log : %s", err))
+		}
+		d.nextSeq++
+		entries = append(entries, &entry{
+			hdr:     hdr,
+			seq:     seq,
+			payload: payload,
+		})
+	}
+	if err := d.wal.Sync(); err != nil {
+		return d.rollbackWAL(offset, status.Errorf(codes.Unavailable, "failed to sync write-ahead log : %s", err))
+	}
+	for _, e := range entries {
+		d.head = append(d.head, e)
+		d.track(e)
+	}
+	if d.opts.MaxWALSize > 0 && d.walSize >= d.opts.MaxWALSize {
+		if err := d.flushLocked(); err != nil {
+			logrus.Errorf("failed to flush write-ahead log : %s", err)
+		}
+	}
+	return nil
+}
+
+// rollbackWAL truncates the write-ahead log back to offset after a failed write, assumes the lock is held
+func (d *ProfileDiskStorage) rollbackWAL(offset int64, cause error) error {
+	if err := d.wal.Truncate(offset); err != nil {
+		logrus.Errorf("failed to truncate write-ahead log after failed write : %s", err)
+		return cause
+	}
+	if _, err := d.wal.Seek(offset, io.SeekStart); err != nil {
+		logrus.Errorf("failed to seek write-ahead log after failed write : %s", err)
+		return cause
+	}
+	d.walSize = offset
+	return cause
+}
+
+// Flush moves all profiles from the write-ahead log into segment files
+func (d *ProfileDiskStorage) Flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.flushLocked()
+}
+
+func (d *ProfileDiskStorage) flushLocked() error {
+	if len(d.head) == 0 {
+		return nil
+	}
+	partitionNanos := d.opts.PartitionDuration.Nanoseconds()
+	partitions := map[int64][]*entry{}
+	for _, e := range d.head {
+		p := e.hdr.Start - e.hdr.Start%partitionNanos
+		partitions[p] = append(partitions[p], e)
+	}
+	keys := make([]int64, 0, len(partitions))
+	for p := range partitions {
+		keys = append(keys, p)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	for _, p := range keys {
+		entries := partitions[p]
+		// segment names must never be reused, since compaction replaces existing segments
+		name := fmt.Sprintf("%d-%d%s", p, d.nextSeq, segmentExt)
+		d.nextSeq++
+		offsets, err := d.writeSegment(name, entries)
+		if err != nil {
+			// partitions written so far are kept, the write-ahead log still holds them until the next flush
+			// but replaying it skips records already flushed into segments
+			d.head = slices.DeleteFunc(d.head, func(e *entry) bool {
+				return e.segment != ""
+			})
+			return err
+		}
+		path := filepath.Join(d.dir, segmentDir, name)
+		for i, e := range entries {
+			e.payload = nil
+			e.segment = path
+			e.offset = offsets[i]
+		}
+	}
+	if err := syncDir(filepath.Join(d.dir, segmentDir)); err != nil {
+		return err
+	}
+	d.head = nil
+	if err := d.wal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := d.wal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	d.walSize = 0
+	return d.wal.Sync()
+}
+
+func (d *ProfileDiskStorage) writeSegment(name string, entries []*entry) ([]int64, error) {
+	tmpPath := filepath.Join(d.dir, segmentDir, name+tmpExt)
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make([]int64, 0, len(entries))
+	var offset int64
+	for _, e := range entries {
+		offsets = append(offsets, offset)
+		n, err := writeFrame(f, e.payload)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		offset += int64(n)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return offsets, os.Rename(tmpPath, filepath.Join(d.dir, segmentDir, name))
+}
+
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// load reads the profile data of an entry, either from memory or its segment file
+func (e *entry) load() (*profile.Profile, error) {
+	payload := e.payload
+	if payload == nil {
+		var err error
+		payload, err = readFrameAt(e.segment, e.offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, _, data, err := decodeSequenced(payload)
+	if err != nil {
+		return nil, err
+	}
+	return profile.ParseData(data)
+}
+
+func readFrameAt(path string, offset int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	hdr := make([]byte, frameHeaderSize)
+	if _, err := f.ReadAt(hdr, offset); err != nil {
+		return nil, err
+	}
+	size := int64(binary.LittleEndian.Uint32(hdr[0:4]))
+	if offset+frameHeaderSize+size > info.Size() {
+		return nil, errCorruptFrame
+	}
+	payload := make([]byte, size)
+	if _, err := f.ReadAt(payload, offset+frameHeaderSize); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(hdr[4:8]) {
+		return nil, errCorruptFrame
+	}
+	return payload, nil
+}
+
+func (d *ProfileDiskStorage) Get(ctx context.Context, instanceId, profileType string, start, end time.Time) (*profile.Profile, error) {
+	retProfiles, err := d.Range(ctx, instanceId, profileType, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(retProfiles) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no %s profiles for %s in time range", profileType, instanceId)
+	}
+	return mergeProfiles(retProfiles)
+}
+
+func (d *ProfileDiskStorage) Range(ctx context.Context, instanceId, profileType string, start, end time.Time) ([]*profile.Profile, error) {
+	d.mu.RLock()
+	types, ok := d.index[instanceId]
+	if !ok {
+		d.mu.RUnlock()
+		return nil, status.Errorf(codes.NotFound, "instance not found")
+	}
+	entries, ok := types[profileType]
+	if !ok {
+		d.mu.RUnlock()
+		return nil, status.Errorf(codes.NotFound, "profile type not found for instanceId")
+	}
+	ret, err := loadRange(entries, start, end)
+	d.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].TimeNanos < ret[j].TimeNanos
+	})
+	return ret, nil
+}
+
+// Select merges the profiles of all instances whose labels match the selector
+func (d *ProfileDiskStorage) Select(ctx context.Context, sel storage.Selector, profileType string, start, end time.Time) (*profile.Profile, error) {
+	d.mu.RLock()
+	retProfiles := []*profile.Profile{}
+	for _, id := range d.labelIndex.Select(sel) {
+		profs, err := loadRange(d.index[id][profileType], start, end)
+		if err != nil {
+			d.mu.RUnlock()
+			return nil, err
+		}
+		retProfiles = append(retProfiles, profs...)
+	}
+	d.mu.RUnlock()
+	if len(retProfiles) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no %s profiles match selector %s", profileType, sel)
+	}
+	return mergeProfiles(retProfiles)
+}
+
+// Delete removes the profiles of the matching instances overlapping the time range
+func (d *ProfileDiskStorage) Delete(ctx context.Context, sel storage.Selector, profileType string, start, end time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// removing profiles rewrites the segments holding them, so the write-ahead log is flushed first
+	if err := d.flushLocked(); err != nil {
+		return 0, status.Errorf(codes.Unavailable, "failed to flush write-ahead log : %s", err)
+	}
+	startNanos, endNanos := start.UnixNano(), end.UnixNano()
+	removed := map[*entry]struct{}{}
+	for _, id := range d.labelIndex.Select(sel) {
+		for pType, entries := range d.index[id] {
+			if profileType != "" && pType != profileType {
+				continue
+			}
+			for _, e := range entries {
+				if e.hdr.Start > endNanos || e.hdr.End < startNanos {
+					continue
+				}
+				removed[e] = struct{}{}
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return 0, nil
+	}
+	if err := d.rewriteLocked(removed, nil); err != nil {
+		return 0, status.Errorf(codes.Internal, "failed to rewrite segments : %s", err)
+	}
+	return len(removed), nil
+}
+
+// loadRange reads the profiles of the entries overlapping the time range, assumes the lock is held
+func loadRange(entries []*entry, start, end time.Time) ([]*profile.Profile, error) {
+	startNanos, endNanos := start.UnixNano(), end.UnixNano()
+	ret := []*profile.Profile{}
+	for _, e := range entries {
+		if e.hdr.Start > endNanos || e.hdr.End < startNanos {
+			continue
+		}
+		prof, err := e.load()
+		if err != nil {
+			return nil, status.Errorf(codes.DataLoss, "failed to read stored profile : %s", err)
+		}
+		ret = append(ret, prof)
+	}
+	return ret, nil
+}
+
+func mergeProfiles(profs []*profile.Profile) (*profile.Profile, error) {
+	ret, err := profile.Merge(profs)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to merge profiles: %s, profiles are incompatible", err)
+	}
+	if valid := ret.CheckValid(); valid != nil {
+		return nil, status.Error(codes.FailedPrecondition, "invalid profile after merge")
+	}
+	return ret, nil
+}
+
+func rangeFromProfile(prof *profile.Profile) (start, end time.Time) {
+	dur := prof.DurationNanos
+	profStart := prof.TimeNanos
+
+	return time.Unix(0, profStart), time.Unix(0, profStart+dur)
+}
+
+// seenRange returns the time range covered by the entries
+func seenRange(entries []*entry) (first, last time.Time) {
+	var start, end int64
+	for i, e := range entries {
+		if i == 0 || e.hdr.Start < start {
+			start = e.hdr.Start
+		}
+		if i == 0 || e.hdr.End > end {
+			end = e.hdr.End
+		}
+	}
+	return time.Unix(0, start), time.Unix(0, end)
+}
+
+func (d *ProfileDiskStorage) ListInstances(ctx context.Context) ([]storage.InstanceInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	ret := make([]storage.InstanceInfo, 0, len(d.index))
+	for id, types := range d.index {
+		info := storage.InstanceInfo{
+			Id:     id,
+			Labels: d.labelIndex.Labels(id),
+		}
+		for _, entries := range types {
+			first, last := seenRange(entries)
+			if info.FirstSeen.IsZero() || first.Before(info.FirstSeen) {
+				info.FirstSeen = first
+			}
+			if last.After(info.LastSeen) {
+				info.LastSeen = last
+			}
+		}
+		ret = append(ret, info)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Id < ret[j].Id })
+	return ret, nil
+}
+
+func (d *ProfileDiskStorage) ListProfileTypes(ctx context.Context, instanceId string) ([]storage.ProfileTypeInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	types, ok := d.index[instanceId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "instance not found")
+	}
+	ret := make([]storage.ProfileTypeInfo, 0, len(types))
+	for pType, entries := range types {
+		first, last := seenRange(entries)
+		ret = append(ret, storage.ProfileTypeInfo{
+			Type:      pType,
+			FirstSeen: first,
+			LastSeen:  last,
+			Count:     len(entries),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Type < ret[j].Type })
+	return ret, nil
+}
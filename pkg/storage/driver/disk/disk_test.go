@@ -0,0 +1,370 @@
+package disk
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexandreLamarre/pprof-server/pkg/storage"
+	"github.com/google/pprof/profile"
+)
+
+func testProfile(start time.Time, value int64) *profile.Profile {
+	fn := &profile.Function{ID: 1, Name: "main.work", Filename: "main.go"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn, Line: 10}}}
+	return &profile.Profile{
+		SampleType:    []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		PeriodType:    &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:        1,
+		TimeNanos:     start.UnixNano(),
+		DurationNanos: (10 * time.Second).Nanoseconds(),
+		Function:      []*profile.Function{fn},
+		Location:      []*profile.Location{loc},
+		Sample:        []*profile.Sample{{Location: []*profile.Location{loc}, Value: []int64{value}}},
+	}
+}
+
+func testOptions() Options {
+	opts := DefaultOptions()
+	opts.FlushInterval = 0
+	return opts
+}
+
+func put(t *testing.T, d *ProfileDiskStorage, id string, start time.Time, value int64) {
+	t.Helper()
+	if err := d.Put(context.Background(), id, "cpu", map[string]string{"env": "test"}, []*profile.Profile{testProfile(start, value)}); err != nil {
+		t.Fatalf("put : %s", err)
+	}
+}
+
+func get(t *testing.T, d *ProfileDiskStorage, id string) string {
+	t.Helper()
+	prof, err := d.Get(context.Background(), id, "cpu", time.Unix(0, 0), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("get : %s", err)
+	}
+	return prof.String()
+}
+
+// reopen simulates a crash : the store is abandoned without Close, flushing or truncating its write-ahead log
+func reopen(t *testing.T, dir string) *ProfileDiskStorage {
+	t.Helper()
+	d, err := NewProfileDiskStorage(dir, testOptions())
+	if err != nil {
+		t.Fatalf("reopen : %s", err)
+	}
+	return d
+}
+
+func TestReplayAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	d := reopen(t, dir)
+	now := time.Now().Add(-time.Minute)
+	put(t, d, "a", now, 1)
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// the second profile only lives in the write-ahead log
+	put(t, d, "a", now.Add(time.Second), 2)
+	before := get(t, d, "a")
+
+	d = reopen(t, dir)
+	if after := get(t, d, "a"); after != before {
+		t.Errorf("profile changed after replay\nbefore : %s\nafter : %s", before, after)
+	}
+	// profiles flushed before the crash and still in the log are not replayed twice
+	types, err := d.ListProfileTypes(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 1 || types[0].Count != 2 {
+		t.Errorf("expected 2 stored profiles after replay, got %+v", types)
+	}
+}
+
+func TestReplayTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	d := reopen(t, dir)
+	now := time.Now().Add(-time.Minute)
+	put(t, d, "a", now, 1)
+	before := get(t, d, "a")
+
+	// crash in the middle of appending a frame
+	wal, err := os.OpenFile(filepath.Join(dir, walDir, walFile), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	torn := binary.LittleEndian.AppendUint32(nil, 1024)
+	torn = binary.LittleEndian.AppendUint32(torn, 0)
+	torn = append(torn, []byte("partial")...)
+	if _, err := wal.Write(torn); err != nil {
+		t.Fatal(err)
+	}
+	wal.Close()
+
+	d = reopen(t, dir)
+	if after := get(t, d, "a"); after != before {
+		t.Errorf("profile changed after replay\nbefore : %s\nafter : %s", before, after)
+	}
+	// profiles acknowledged after the torn write must survive the next crash
+	put(t, d, "a", now.Add(time.Second), 2)
+	before = get(t, d, "a")
+	d = reopen(t, dir)
+	if after := get(t, d, "a"); after != before {
+		t.Errorf("profile changed after second replay\nbefore : %s\nafter : %s", before, after)
+	}
+}
+
+func TestFrameReader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for _, payload := range []string{"first", "second"} {
+		if _, err := writeFrame(buf, []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	valid := buf.Len()
+
+	testCases := []struct {
+		name     string
+		trailer  []byte
+		expected []string
+	}{
+		{
+			name:     "clean",
+			expected: []string{"first", "second"},
+		},
+		{
+			name:     "truncated header",
+			trailer:  []byte{1, 2, 3},
+			expected: []string{"first", "second"},
+		},
+		{
+			name:     "length past end of data",
+			trailer:  []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0},
+			expected: []string{"first", "second"},
+		},
+		{
+			name:     "bad checksum",
+			trailer:  []byte{1, 0, 0, 0, 0, 0, 0, 0, 'x'},
+			expected: []string{"first", "second"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := append(bytes.Clone(buf.Bytes()), tc.trailer...)
+			r := newFrameReader(bytes.NewReader(data), int64(len(data)))
+			got := []string{}
+			for {
+				_, payload, err := r.next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					if len(tc.trailer) == 0 || !errors.Is(err, errCorruptFrame) {
+						t.Fatalf("unexpected error : %s", err)
+					}
+					break
+				}
+				got = append(got, string(payload))
+			}
+			if len(got) != len(tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("expected %v, got %v", tc.expected, got)
+				}
+			}
+			if r.offset != int64(valid) {
+				t.Errorf("expected valid offset %d, got %d", valid, r.offset)
+			}
+		})
+	}
+}
+
+func TestRollbackFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	d := reopen(t, dir)
+	now := time.Now().Add(-time.Minute)
+	put(t, d, "a", now, 1)
+
+	// a write that failed half way through a frame
+	offset := d.walSize
+	n, err := d.wal.Write([]byte{0xff, 0, 0, 0, 1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.walSize += int64(n)
+	d.rollbackWAL(offset, errors.New("write failed"))
+
+	put(t, d, "a", now.Add(time.Second), 2)
+	before := get(t, d, "a")
+	d = reopen(t, dir)
+	if after := get(t, d, "a"); after != before {
+		t.Errorf("profiles acknowledged after a failed write were lost\nbefore : %s\nafter : %s", before, after)
+	}
+}
+
+func TestDeleteSurvivesCrashBeforeSegmentRemoval(t *testing.T) {
+	dir := t.TempDir()
+	d := reopen(t, dir)
+	now := time.Now().Add(-time.Minute)
+	put(t, d, "a", now, 1)
+	put(t, d, "b", now, 2)
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// keep a copy of the segments the delete is going to remove
+	segments := map[string][]byte{}
+	files, err := os.ReadDir(filepath.Join(dir, segmentDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(dir, segmentDir, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		segments[f.Name()] = data
+	}
+
+	sel, err := storage.ParseSelector(`{instance="a"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := d.Delete(context.Background(), sel, "", time.Unix(0, 0), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 deleted profile, got %d", deleted)
+	}
+	before := get(t, d, "b")
+
+	// crash after writing the rewritten segments, before the old ones were removed
+	for name, data := range segments {
+		if err := os.WriteFile(filepath.Join(dir, segmentDir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d = reopen(t, dir)
+	if _, err := d.Get(context.Background(), "a", "cpu", time.Unix(0, 0), time.Now()); err == nil {
+		t.Error("deleted profiles came back after replay")
+	}
+	if after := get(t, d, "b"); after != before {
+		t.Errorf("profile changed after replay\nbefore : %s\nafter : %s", before, after)
+	}
+}
+
+func TestFlushFailurePartway(t *testing.T) {
+	dir := t.TempDir()
+	d := reopen(t, dir)
+	partition := d.opts.PartitionDuration
+	first := time.Now().Add(-3 * partition).Truncate(partition)
+	second := first.Add(partition)
+	put(t, d, "a", first, 1)
+	put(t, d, "a", second, 2)
+	before := get(t, d, "a")
+
+	// the second partition's segment can't be created, e.g. the disk is full
+	blocked := filepath.Join(dir, segmentDir, fmt.Sprintf("%d-%d%s%s", second.UnixNano(), d.nextSeq+1, segmentExt, tmpExt))
+	if err := os.Mkdir(blocked, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Flush(); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+	if after := get(t, d, "a"); after != before {
+		t.Errorf("profile changed after a failed flush\nbefore : %s\nafter : %s", before, after)
+	}
+
+	if err := os.Remove(blocked); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if after := get(t, d, "a"); after != before {
+		t.Errorf("profile changed after retrying the flush\nbefore : %s\nafter : %s", before, after)
+	}
+	d = reopen(t, dir)
+	if after := get(t, d, "a"); after != before {
+		t.Errorf("profile changed after reopening\nbefore : %s\nafter : %s", before, after)
+	}
+	types, err := d.ListProfileTypes(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 1 || types[0].Count != 2 {
+		t.Errorf("expected 2 stored profiles, got %+v", types)
+	}
+}
+
+func TestDeleteSurvivesFailedSegmentRemoval(t *testing.T) {
+	dir := t.TempDir()
+	d := reopen(t, dir)
+	now := time.Now().Add(-time.Minute)
+	for _, id := range []string{"a", "b", "c"} {
+		put(t, d, id, now, 1)
+	}
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := os.ReadDir(filepath.Join(dir, segmentDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected a single segment, got %d", len(files))
+	}
+	leftover := filepath.Join(dir, segmentDir, files[0].Name())
+	data, err := os.ReadFile(leftover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	del := func(id string) {
+		sel, err := storage.ParseSelector(fmt.Sprintf(`{instance=%q}`, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := d.Delete(context.Background(), sel, "", time.Unix(0, 0), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	del("a")
+
+	// the segment holding a's profile can't be removed, e.g. a non-empty directory took its place,
+	// while the segment holding a's tombstone is rewritten
+	if err := os.MkdirAll(filepath.Join(leftover, "busy"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	del("b")
+	before := get(t, d, "c")
+
+	if err := os.RemoveAll(leftover); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(leftover, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d = reopen(t, dir)
+	for _, id := range []string{"a", "b"} {
+		if _, err := d.Get(context.Background(), id, "cpu", time.Unix(0, 0), time.Now()); err == nil {
+			t.Errorf("deleted profiles of %s came back after reopening", id)
+		}
+	}
+	if after := get(t, d, "c"); after != before {
+		t.Errorf("profile changed after reopening\nbefore : %s\nafter : %s", before, after)
+	}
+	if _, err := os.Stat(leftover); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected the leftover segment to be removed when reopening, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package disk
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// frames are laid out as : [ 4 byte payload length | 4 byte crc32c of payload | payload ]
+const frameHeaderSize = 8
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var errCorruptFrame = errors.New("corrupt frame")
+
+// recordHeader describes a single stored profile
+type recordHeader struct {
+	InstanceId  string            `json:"instanceId"`
+	ProfileType string            `json:"type"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	// unix nanos
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	// sequence numbers of the records compacted into this one
+	Replaces []uint64 `json:"replaces,omitempty"`
+	// tombstones hold no profile, they only record the removal of the records they replace
+	Tombstone bool `json:"tombstone,omitempty"`
+	// names of the segment files the replaced records were removed from
+	Segments []string `json:"segments,omitempty"`
+}
+
+// record payloads are laid out as : [ uvarint header length | json header | gzipped pprof data ]
+func encodeRecord(hdr recordHeader, data []byte) ([]byte, error) {
+	hdrData, err := json.Marshal(hdr)
+	if err != nil {
+		return nil, err
+	}
+	payload := binary.AppendUvarint(nil, uint64(len(hdrData)))
+	payload = append(payload, hdrData...)
+	payload = append(payload, data...)
+	return payload, nil
+}
+
+func decodeRecord(payload []byte) (recordHeader, []byte, error) {
+	hdr := recordHeader{}
+	hdrLen, n := binary.Uvarint(payload)
+	if n <= 0 || uint64(len(payload)-n) < hdrLen {
+		return hdr, nil, errCorruptFrame
+	}
+	if err := json.Unmarshal(payload[n:n+int(hdrLen)], &hdr); err != nil {
+		return hdr, nil, fmt.Errorf("%w : %s", errCorruptFrame, err)
+	}
+	return hdr, payload[n+int(hdrLen):], nil
+}
+
+func writeFrame(w io.Writer, payload []byte) (int, error) {
+	buf := make([]byte, frameHeaderSize, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, castagnoli))
+	buf = append(buf, payload...)
+	return w.Write(buf)
+}
+
+// frameReader reads consecutive frames, keeping track of the offset of the last valid frame
+type frameReader struct {
+	r      *bufio.Reader
+	offset int64
+	// size of the underlying data, frames claiming to extend past it are corrupt
+	size int64
+}
+
+func newFrameReader(r io.Reader, size int64) *frameReader {
+	return &frameReader{
+		r:    bufio.NewReader(r),
+		size: size,
+	}
+}
+
+// next returns the offset and payload of the next frame.
+// Returns io.EOF when the reader is cleanly exhausted, and errCorruptFrame
+// when the remaining data is a torn or corrupt write.
+func (f *frameReader) next() (int64, []byte, error) {
+	hdr := make([]byte, frameHeaderSize)
+	if _, err := io.ReadFull(f.r, hdr); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, io.EOF
+		}
+		return 0, nil, errCorruptFrame
+	}
+	size := binary.LittleEndian.Uint32(hdr[0:4])
+	sum := binary.LittleEndian.Uint32(hdr[4:8])
+	// a corrupt length must not allocate more than what is left to read
+	if f.offset+frameHeaderSize+int64(size) > f.size {
+		return 0, nil, errCorruptFrame
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(f.r, payload); err != nil {
+		return 0, nil, errCorruptFrame
+	}
+	if crc32.Checksum(payload, castagnoli) != sum {
+		return 0, nil, errCorruptFrame
+	}
+	offset := f.offset
+	f.offset += frameHeaderSize + int64(size)
+	return offset, payload, nil
+}
@@ -2,10 +2,14 @@ package mem
 
 import (
 	"context"
+	"maps"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/alexandreLamarre/pprof-server/pkg/storage"
 	"github.com/google/pprof/profile"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -18,47 +22,291 @@ func rangeFromProfile(prof *profile.Profile) (start, end time.Time) {
 
 }
 
+type Option func(*profileMemStorage)
+
+// WithMemoryLimit bounds the estimated size of stored profiles, evicting the oldest
+// profiles once the limit is reached. A limit <= 0 is unbounded.
+func WithMemoryLimit(bytes int64) Option {
+	return func(m *profileMemStorage) {
+		m.maxBytes = bytes
+	}
+}
+
+// WithRetention sets the policies applied to stored profiles when compacting
+func WithRetention(policies storage.RetentionPolicies) Option {
+	return func(m *profileMemStorage) {
+		m.retention = policies
+	}
+}
+
 type profileMemStorage struct {
+	mu sync.RWMutex
 	// id -> storedProfiles
-	buffer map[string]*storedProfiles
+	buffer     map[string]*storedProfiles
+	labelIndex *storage.LabelIndex
+
+	maxBytes  int64
+	usedBytes int64
+	evicted   storage.EvictionStats
+
+	retention storage.RetentionPolicies
 }
 
-func NewProfileMemStorage() storage.ProfileStore {
-	return &profileMemStorage{
-		buffer: map[string]*storedProfiles{},
+var _ storage.EvictionReporter = (*profileMemStorage)(nil)
+var _ storage.Compactor = (*profileMemStorage)(nil)
+
+func NewProfileMemStorage(opts ...Option) storage.ProfileStore {
+	m := &profileMemStorage{
+		buffer:     map[string]*storedProfiles{},
+		labelIndex: storage.NewLabelIndex(),
+		retention:  storage.DefaultRetentionPolicies(),
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 type storedProfiles struct {
-	Metadata struct{}
-	Labels   map[string]string
-	// profile type ( mutex, cpu, etc.. ) -> profile
-	Profiles map[string][]*profile.Profile
+	Labels map[string]string
+	// profile type ( mutex, cpu, etc.. ) -> profiles, sorted by start time
+	Profiles map[string][]*storedProfile
+}
+
+type storedProfile struct {
+	prof       *profile.Profile
+	start, end time.Time
+	size       int64
+}
+
+func newStoredProfile(p *profile.Profile) *storedProfile {
+	start, end := rangeFromProfile(p)
+	return &storedProfile{
+		prof:  p,
+		start: start,
+		end:   end,
+		size:  estimateSize(p),
+	}
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// estimateSize approximates the memory held by a profile by its uncompressed encoded size
+func estimateSize(prof *profile.Profile) int64 {
+	w := &countingWriter{}
+	if err := prof.WriteUncompressed(w); err != nil {
+		return 0
+	}
+	return w.n
 }
 
 func (m *profileMemStorage) Put(ctx context.Context,
 	instanceId, profileType string,
 	metadata map[string]string,
 	prof []*profile.Profile) error {
+	metadata = storage.ExportLabels(metadata)
+	stored := make([]*storedProfile, 0, len(prof))
+	for _, p := range prof {
+		stored = append(stored, newStoredProfile(p))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if _, ok := m.buffer[instanceId]; !ok {
 		m.buffer[instanceId] = &storedProfiles{
 			Labels:   map[string]string{},
-			Profiles: map[string][]*profile.Profile{},
+			Profiles: map[string][]*storedProfile{},
 		}
 	}
-	if _, ok := m.buffer[instanceId].Profiles[instanceId]; !ok {
-		m.buffer[instanceId].Profiles[profileType] = prof
+	// TODO : metadata should be segmented per profile, and metadata changes tracked in a separate index
+	// TODO : also we need an index to keep track of what ranges of profiles are compatible.
+	m.buffer[instanceId].Labels = metadata
+	m.labelIndex.Set(instanceId, metadata)
+	profs := append(m.buffer[instanceId].Profiles[profileType], stored...)
+	// late or backfilled profiles are kept in start time order, eviction drops the first profile
+	sort.SliceStable(profs, func(i, j int) bool { return profs[i].start.Before(profs[j].start) })
+	m.buffer[instanceId].Profiles[profileType] = profs
+	for _, s := range stored {
+		m.usedBytes += s.size
+	}
+	m.evictLocked()
+	return nil
+}
 
-		// TODO : metadata should be segmented per profile, and metadata changes tracked in a separate index
-		// TODO : also we need an index to keep track of what ranges of profiles are compatible.
-		m.buffer[instanceId].Labels = metadata
-	} else {
-		// merge profiles
-		m.buffer[instanceId].Profiles[profileType] = append(m.buffer[instanceId].Profiles[instanceId], prof...)
+// evictLocked drops the oldest profile of any instance/type until the store fits within its memory budget.
+// Profiles of an instance/type are sorted by start time, so the oldest is the first one.
+func (m *profileMemStorage) evictLocked() {
+	if m.maxBytes <= 0 {
+		return
+	}
+	var evicted storage.EvictionStats
+	defer func() {
+		if evicted.Profiles > 0 {
+			logrus.Warnf(
+				"evicted %d profiles (%d bytes) to stay within the %d bytes memory limit, %d profiles (%d bytes) evicted since startup",
+				evicted.Profiles, evicted.Bytes, m.maxBytes, m.evicted.Profiles, m.evicted.Bytes,
+			)
+		}
+	}()
+	for m.usedBytes > m.maxBytes {
+		var oldestId, oldestType string
+		var oldest *storedProfile
+		for id, profs := range m.buffer {
+			for pType, stored := range profs.Profiles {
+				if len(stored) == 0 {
+					continue
+				}
+				if oldest == nil || stored[0].start.Before(oldest.start) {
+					oldestId, oldestType, oldest = id, pType, stored[0]
+				}
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		profs := m.buffer[oldestId]
+		profs.Profiles[oldestType] = profs.Profiles[oldestType][1:]
+		if len(profs.Profiles[oldestType]) == 0 {
+			delete(profs.Profiles, oldestType)
+		}
+		if len(profs.Profiles) == 0 {
+			delete(m.buffer, oldestId)
+			m.labelIndex.Delete(oldestId)
+		}
+		m.usedBytes -= oldest.size
+		m.evicted.Profiles++
+		m.evicted.Bytes += oldest.size
+		evicted.Profiles++
+		evicted.Bytes += oldest.size
+		logrus.Debugf("evicted %s profile for %s (%d bytes) to stay within memory limit", oldestType, oldestId, oldest.size)
+	}
+}
+
+// Compact deletes profiles past their retention and merges older profiles into coarser buckets
+func (m *profileMemStorage) Compact(ctx context.Context, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, profs := range m.buffer {
+		for pType, stored := range profs.Profiles {
+			expired, groups := storage.PlanCompaction(m.retention.For(pType), now, stored, func(s *storedProfile) (time.Time, time.Time) {
+				return s.start, s.end
+			})
+			if len(expired) == 0 && len(groups) == 0 {
+				continue
+			}
+			removed := map[*storedProfile]struct{}{}
+			for _, s := range expired {
+				removed[s] = struct{}{}
+			}
+			merged := []*storedProfile{}
+			for _, group := range groups {
+				toMerge := make([]*profile.Profile, 0, len(group))
+				for _, s := range group {
+					toMerge = append(toMerge, s.prof)
+				}
+				ret, err := profile.Merge(toMerge)
+				if err != nil {
+					logrus.Warnf("skipping compaction of %s profiles for %s : %s", pType, id, err)
+					continue
+				}
+				for _, s := range group {
+					removed[s] = struct{}{}
+				}
+				// the merged profile keeps the summed durations, the stored range is the time range it covers
+				compacted := newStoredProfile(ret)
+				compacted.start, compacted.end = storage.Span(toMerge)
+				merged = append(merged, compacted)
+			}
+
+			kept := make([]*storedProfile, 0, len(stored)-len(removed)+len(merged))
+			for _, s := range stored {
+				if _, ok := removed[s]; ok {
+					m.usedBytes -= s.size
+					continue
+				}
+				kept = append(kept, s)
+			}
+			for _, s := range merged {
+				m.usedBytes += s.size
+				kept = append(kept, s)
+			}
+			sort.SliceStable(kept, func(i, j int) bool { return kept[i].start.Before(kept[j].start) })
+			if len(kept) == 0 {
+				delete(profs.Profiles, pType)
+				continue
+			}
+			profs.Profiles[pType] = kept
+		}
+		if len(profs.Profiles) == 0 {
+			delete(m.buffer, id)
+			m.labelIndex.Delete(id)
+		}
 	}
 	return nil
 }
+
+// Delete removes the profiles of the matching instances overlapping the time range
+func (m *profileMemStorage) Delete(ctx context.Context, sel storage.Selector, profileType string, start, end time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := 0
+	for _, id := range m.labelIndex.Select(sel) {
+		profs := m.buffer[id]
+		for pType, stored := range profs.Profiles {
+			if profileType != "" && pType != profileType {
+				continue
+			}
+			kept := make([]*storedProfile, 0, len(stored))
+			for _, s := range stored {
+				if s.start.After(end) || s.end.Before(start) {
+					kept = append(kept, s)
+					continue
+				}
+				m.usedBytes -= s.size
+				deleted++
+			}
+			if len(kept) == 0 {
+				delete(profs.Profiles, pType)
+				continue
+			}
+			profs.Profiles[pType] = kept
+		}
+		if len(profs.Profiles) == 0 {
+			delete(m.buffer, id)
+			m.labelIndex.Delete(id)
+		}
+	}
+	return deleted, nil
+}
+
+// Evicted reports the total amount of profiles dropped to stay within the memory limit
+func (m *profileMemStorage) Evicted() storage.EvictionStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.evicted
+}
+
 func (m *profileMemStorage) Get(ctx context.Context, instanceId, profileType string, start, end time.Time) (*profile.Profile, error) {
+	retProfiles, err := m.Range(ctx, instanceId, profileType, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(retProfiles) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no %s profiles for %s in time range", profileType, instanceId)
+	}
+	return mergeProfiles(retProfiles)
+}
+
+func (m *profileMemStorage) Range(ctx context.Context, instanceId, profileType string, start, end time.Time) ([]*profile.Profile, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	profs, ok := m.buffer[instanceId]
 	if !ok {
 		return nil, status.Errorf(codes.NotFound, "instance not found")
@@ -66,22 +314,42 @@ func (m *profileMemStorage) Get(ctx context.Context, instanceId, profileType str
 	if _, ok := profs.Profiles[profileType]; !ok {
 		return nil, status.Errorf(codes.NotFound, "profile type not found for instanceId")
 	}
+	// profiles are stored sorted by start time
+	return inRange(profs.Profiles[profileType], start, end), nil
+}
+
+// Select merges the profiles of all instances whose labels match the selector
+func (m *profileMemStorage) Select(ctx context.Context, sel storage.Selector, profileType string, start, end time.Time) (*profile.Profile, error) {
+	m.mu.RLock()
 	retProfiles := []*profile.Profile{}
-	for _, profile := range profs.Profiles[profileType] {
-		pStart, pEnd := rangeFromProfile(profile)
+	for _, id := range m.labelIndex.Select(sel) {
+		retProfiles = append(retProfiles, inRange(m.buffer[id].Profiles[profileType], start, end)...)
+	}
+	m.mu.RUnlock()
+	if len(retProfiles) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no %s profiles match selector %s", profileType, sel)
+	}
+	return mergeProfiles(retProfiles)
+}
 
-		if pStart.After(end) {
+func inRange(stored []*storedProfile, start, end time.Time) []*profile.Profile {
+	ret := []*profile.Profile{}
+	for _, s := range stored {
+		if s.start.After(end) {
 			continue
 		}
-		if pEnd.Before(start) {
+		if s.end.Before(start) {
 			continue
 		}
-		retProfiles = append(retProfiles, profile)
+		ret = append(ret, s.prof)
 	}
+	return ret
+}
 
+func mergeProfiles(profs []*profile.Profile) (*profile.Profile, error) {
 	// TODO : block profiles don't play nice with merge, need to check implementation of `-base` flag to see what they do there
-	// TODO : also, for good measure, need to check implementation of `diff_base` flag.
-	ret, err := profile.Merge(retProfiles)
+	// stored profiles are never mutated, so merging them outside the lock is safe
+	ret, err := profile.Merge(profs)
 	if err != nil {
 		return nil, status.Errorf(codes.FailedPrecondition, "failed to merge profiles: %s, profiles are incompatible", err)
 	}
@@ -90,3 +358,61 @@ func (m *profileMemStorage) Get(ctx context.Context, instanceId, profileType str
 	}
 	return ret, nil
 }
+
+// seenRange returns the time range covered by the stored profiles
+func seenRange(stored []*storedProfile) (first, last time.Time) {
+	for i, s := range stored {
+		if i == 0 || s.start.Before(first) {
+			first = s.start
+		}
+		if i == 0 || s.end.After(last) {
+			last = s.end
+		}
+	}
+	return first, last
+}
+
+func (m *profileMemStorage) ListInstances(ctx context.Context) ([]storage.InstanceInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ret := make([]storage.InstanceInfo, 0, len(m.buffer))
+	for id, profs := range m.buffer {
+		info := storage.InstanceInfo{
+			Id:     id,
+			Labels: maps.Clone(profs.Labels),
+		}
+		for _, stored := range profs.Profiles {
+			first, last := seenRange(stored)
+			if info.FirstSeen.IsZero() || first.Before(info.FirstSeen) {
+				info.FirstSeen = first
+			}
+			if last.After(info.LastSeen) {
+				info.LastSeen = last
+			}
+		}
+		ret = append(ret, info)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Id < ret[j].Id })
+	return ret, nil
+}
+
+func (m *profileMemStorage) ListProfileTypes(ctx context.Context, instanceId string) ([]storage.ProfileTypeInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	profs, ok := m.buffer[instanceId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "instance not found")
+	}
+	ret := make([]storage.ProfileTypeInfo, 0, len(profs.Profiles))
+	for pType, stored := range profs.Profiles {
+		first, last := seenRange(stored)
+		ret = append(ret, storage.ProfileTypeInfo{
+			Type:      pType,
+			FirstSeen: first,
+			LastSeen:  last,
+			Count:     len(stored),
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Type < ret[j].Type })
+	return ret, nil
+}
@@ -0,0 +1,77 @@
+package mem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+func testProfile(start time.Time) *profile.Profile {
+	fn := &profile.Function{ID: 1, Name: "main.work"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn}}}
+	return &profile.Profile{
+		SampleType:    []*profile.ValueType{{Type: "samples", Unit: "count"}},
+		PeriodType:    &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		TimeNanos:     start.UnixNano(),
+		DurationNanos: (10 * time.Second).Nanoseconds(),
+		Function:      []*profile.Function{fn},
+		Location:      []*profile.Location{loc},
+		Sample:        []*profile.Sample{{Location: []*profile.Location{loc}, Value: []int64{1}}},
+	}
+}
+
+func TestEvictsOldestProfile(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	size := estimateSize(testProfile(now))
+	m := NewProfileMemStorage(WithMemoryLimit(3 * size)).(*profileMemStorage)
+
+	// the oldest profile arrives last, e.g. a backfill
+	for _, ago := range []time.Duration{time.Minute, 3 * time.Minute, 2 * time.Minute, 5 * time.Minute} {
+		if err := m.Put(ctx, "a", "cpu", map[string]string{}, []*profile.Profile{testProfile(now.Add(-ago))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	profs, err := m.Range(ctx, "a", "cpu", time.Unix(0, 0), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []time.Time{now.Add(-3 * time.Minute), now.Add(-2 * time.Minute), now.Add(-time.Minute)}
+	if len(profs) != len(expected) {
+		t.Fatalf("expected %d profiles, got %d", len(expected), len(profs))
+	}
+	for i, prof := range profs {
+		if prof.TimeNanos != expected[i].UnixNano() {
+			t.Errorf("expected profile %d to start at %s, got %s", i, expected[i], time.Unix(0, prof.TimeNanos))
+		}
+	}
+	if evicted := m.Evicted(); evicted.Profiles != 1 || evicted.Bytes != size {
+		t.Errorf("expected 1 evicted profile of %d bytes, got %+v", size, evicted)
+	}
+}
+
+func TestPutExportsInstanceLabel(t *testing.T) {
+	ctx := context.Background()
+	m := NewProfileMemStorage()
+	labels := map[string]string{"instance": "10.0.0.1:6060", "service": "api"}
+	if err := m.Put(ctx, "a", "cpu", labels, []*profile.Profile{testProfile(time.Now())}); err != nil {
+		t.Fatal(err)
+	}
+	if labels["instance"] != "10.0.0.1:6060" {
+		t.Error("expected the caller's labels not to be modified")
+	}
+	instances, err := m.ListInstances(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(instances))
+	}
+	got := instances[0].Labels
+	if got["instance"] != "" || got["exported_instance"] != "10.0.0.1:6060" || got["service"] != "api" {
+		t.Errorf("expected the instance label to be exported, got %v", got)
+	}
+}
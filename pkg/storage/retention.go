@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/sirupsen/logrus"
+)
+
+// Tier merges profiles older than After into buckets of size Resolution
+type Tier struct {
+	After      time.Duration
+	Resolution time.Duration
+}
+
+type RetentionPolicy struct {
+	// Tiers are applied from the oldest matching After
+	Tiers []Tier
+	// Profiles that ended longer than Retention ago are deleted, 0 keeps profiles forever
+	Retention time.Duration
+}
+
+// DefaultRetentionPolicy keeps raw profiles for an hour, 1 minute merges for a day,
+// 1 hour merges for 30 days
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{
+		Tiers: []Tier{
+			{After: time.Hour, Resolution: time.Minute},
+			{After: 24 * time.Hour, Resolution: time.Hour},
+		},
+		Retention: 30 * 24 * time.Hour,
+	}
+}
+
+// resolution returns the bucket size profiles of the given age are merged into, 0 if they are kept raw
+func (r RetentionPolicy) resolution(age time.Duration) time.Duration {
+	var res time.Duration
+	var after time.Duration = -1
+	for _, t := range r.Tiers {
+		if age >= t.After && t.After > after {
+			after, res = t.After, t.Resolution
+		}
+	}
+	return res
+}
+
+type RetentionPolicies struct {
+	Default RetentionPolicy
+	// profile type -> policy
+	ByType map[string]RetentionPolicy
+}
+
+func DefaultRetentionPolicies() RetentionPolicies {
+	return RetentionPolicies{
+		Default: DefaultRetentionPolicy(),
+		ByType:  map[string]RetentionPolicy{},
+	}
+}
+
+func (r RetentionPolicies) For(profileType string) RetentionPolicy {
+	if p, ok := r.ByType[profileType]; ok {
+		return p
+	}
+	return r.Default
+}
+
+// ParseRetentionPolicy parses policies of the form `[<profileType>=]<after>:<resolution>,...,retain:<duration>`,
+// for example `cpu=1h:1m,24h:1h,retain:720h`. Policies without a profile type replace the default policy.
+func (r *RetentionPolicies) ParseRetentionPolicy(spec string) error {
+	profileType := ""
+	if idx := strings.Index(spec, "="); idx >= 0 {
+		profileType, spec = spec[:idx], spec[idx+1:]
+	}
+	policy := RetentionPolicy{}
+	for _, item := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok {
+			return fmt.Errorf("invalid retention policy item %q, expected <after>:<resolution> or retain:<duration>", item)
+		}
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid retention policy item %q : %w", item, err)
+		}
+		if key == "retain" {
+			policy.Retention = dur
+			continue
+		}
+		after, err := time.ParseDuration(key)
+		if err != nil {
+			return fmt.Errorf("invalid retention policy item %q : %w", item, err)
+		}
+		if dur <= 0 {
+			return fmt.Errorf("invalid retention policy item %q : resolution must be positive", item)
+		}
+		policy.Tiers = append(policy.Tiers, Tier{After: after, Resolution: dur})
+	}
+	if profileType == "" {
+		r.Default = policy
+		return nil
+	}
+	if r.ByType == nil {
+		r.ByType = map[string]RetentionPolicy{}
+	}
+	r.ByType[profileType] = policy
+	return nil
+}
+
+// PlanCompaction splits the stored profiles of a single instance / profile type into
+// the profiles past retention, and groups of profiles that should be merged together.
+// Groups only contain more than one profile.
+func PlanCompaction[T any](
+	policy RetentionPolicy,
+	now time.Time,
+	items []T,
+	span func(T) (start, end time.Time),
+) (expired []T, groups [][]T) {
+	type bucket struct {
+		start      int64
+		resolution time.Duration
+	}
+	buckets := map[bucket][]T{}
+	keys := []bucket{}
+	for _, item := range items {
+		start, end := span(item)
+		age := now.Sub(end)
+		if policy.Retention > 0 && age > policy.Retention {
+			expired = append(expired, item)
+			continue
+		}
+		res := policy.resolution(age)
+		if res <= 0 {
+			continue
+		}
+		b := bucket{start: start.Truncate(res).UnixNano(), resolution: res}
+		if _, ok := buckets[b]; !ok {
+			keys = append(keys, b)
+		}
+		buckets[b] = append(buckets[b], item)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].start < keys[j].start })
+	for _, b := range keys {
+		if len(buckets[b]) > 1 {
+			groups = append(groups, buckets[b])
+		}
+	}
+	return expired, groups
+}
+
+// Span returns the time range covered by the profiles. Profiles merged by profile.Merge keep
+// the sum of the profiled durations instead, which is what rates are normalized by.
+func Span(profs []*profile.Profile) (start, end time.Time) {
+	startNanos, endNanos := profs[0].TimeNanos, profs[0].TimeNanos+profs[0].DurationNanos
+	for _, p := range profs[1:] {
+		startNanos = min(startNanos, p.TimeNanos)
+		endNanos = max(endNanos, p.TimeNanos+p.DurationNanos)
+	}
+	return time.Unix(0, startNanos), time.Unix(0, endNanos)
+}
+
+// Compactor is implemented by stores that can apply retention policies to their profiles
+type Compactor interface {
+	Compact(ctx context.Context, now time.Time) error
+}
+
+// RunCompaction compacts the store every interval until the context is done
+func RunCompaction(ctx context.Context, c Compactor, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			if err := c.Compact(ctx, now); err != nil {
+				logrus.Errorf("failed to compact profiles : %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     string
+		expected RetentionPolicy
+		byType   string
+		err      bool
+	}{
+		{
+			name: "default",
+			spec: "1h:1m,24h:1h,retain:720h",
+			expected: RetentionPolicy{
+				Tiers:     []Tier{{After: time.Hour, Resolution: time.Minute}, {After: 24 * time.Hour, Resolution: time.Hour}},
+				Retention: 720 * time.Hour,
+			},
+		},
+		{
+			name:   "by type",
+			spec:   "cpu=2h:5m",
+			byType: "cpu",
+			expected: RetentionPolicy{
+				Tiers: []Tier{{After: 2 * time.Hour, Resolution: 5 * time.Minute}},
+			},
+		},
+		{
+			name:     "retention only",
+			spec:     "retain:24h",
+			expected: RetentionPolicy{Retention: 24 * time.Hour},
+		},
+		{name: "missing resolution", spec: "1h", err: true},
+		{name: "invalid duration", spec: "1h:soon", err: true},
+		{name: "invalid after", spec: "later:1m", err: true},
+		{name: "zero resolution", spec: "1h:0s", err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			policies := DefaultRetentionPolicies()
+			err := policies.ParseRetentionPolicy(tc.spec)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error parsing %q", tc.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := policies.Default
+			if tc.byType != "" {
+				got = policies.For(tc.byType)
+				if policies.For("other").Retention != DefaultRetentionPolicy().Retention {
+					t.Error("expected other profile types to keep the default policy")
+				}
+			}
+			if got.Retention != tc.expected.Retention || len(got.Tiers) != len(tc.expected.Tiers) {
+				t.Fatalf("expected %+v, got %+v", tc.expected, got)
+			}
+			for i := range got.Tiers {
+				if got.Tiers[i] != tc.expected.Tiers[i] {
+					t.Errorf("expected %+v, got %+v", tc.expected, got)
+				}
+			}
+		})
+	}
+}
+
+type span struct {
+	start, end time.Time
+}
+
+func TestPlanCompaction(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 30, 0, time.UTC)
+	policy := DefaultRetentionPolicy()
+	at := func(ago time.Duration) span {
+		end := now.Add(-ago)
+		return span{start: end.Add(-10 * time.Second), end: end}
+	}
+	items := []span{
+		// raw
+		at(time.Minute),
+		at(2 * time.Minute),
+		// merged into the same minute
+		at(2*time.Hour + 5*time.Second),
+		at(2*time.Hour + 15*time.Second),
+		// alone in its minute
+		at(3 * time.Hour),
+		// merged into the same hour
+		at(48*time.Hour + 10*time.Minute),
+		at(48*time.Hour + 20*time.Minute),
+		// expired
+		at(31 * 24 * time.Hour),
+	}
+	expired, groups := PlanCompaction(policy, now, items, func(s span) (time.Time, time.Time) {
+		return s.start, s.end
+	})
+	if len(expired) != 1 || expired[0] != items[7] {
+		t.Errorf("expected the oldest profile to expire, got %v", expired)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d : %v", len(groups), groups)
+	}
+	// groups are sorted by bucket start
+	if len(groups[0]) != 2 || groups[0][0] != items[5] || groups[0][1] != items[6] {
+		t.Errorf("expected the hourly group first, got %v", groups[0])
+	}
+	if len(groups[1]) != 2 || groups[1][0] != items[2] || groups[1][1] != items[3] {
+		t.Errorf("expected the minute group second, got %v", groups[1])
+	}
+}
+
+func cpuProfile(start time.Time, duration time.Duration, value int64) *profile.Profile {
+	fn := &profile.Function{ID: 1, Name: "main.work"}
+	loc := &profile.Location{ID: 1, Line: []profile.Line{{Function: fn}}}
+	return &profile.Profile{
+		SampleType:    []*profile.ValueType{{Type: "cpu", Unit: "nanoseconds"}},
+		PeriodType:    &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		TimeNanos:     start.UnixNano(),
+		DurationNanos: duration.Nanoseconds(),
+		Function:      []*profile.Function{fn},
+		Location:      []*profile.Location{loc},
+		Sample:        []*profile.Sample{{Location: []*profile.Location{loc}, Value: []int64{value}}},
+	}
+}
+
+func TestSpan(t *testing.T) {
+	start := time.Unix(1000, 0)
+	// 10s profiles scraped every minute
+	profs := []*profile.Profile{
+		cpuProfile(start.Add(2*time.Minute), 10*time.Second, 3),
+		cpuProfile(start, 10*time.Second, 1),
+		cpuProfile(start.Add(time.Minute), 10*time.Second, 2),
+	}
+	merged, err := profile.Merge(profs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.DurationNanos != (30 * time.Second).Nanoseconds() {
+		t.Errorf("expected the profiled durations to be summed, got %s", time.Duration(merged.DurationNanos))
+	}
+	if merged.TimeNanos != start.UnixNano() {
+		t.Errorf("expected the merged profile to start at %s, got %s", start, time.Unix(0, merged.TimeNanos))
+	}
+	var total int64
+	for _, s := range merged.Sample {
+		total += s.Value[0]
+	}
+	if total != 6 {
+		t.Errorf("expected a total of 6, got %d", total)
+	}
+
+	spanStart, spanEnd := Span(profs)
+	if !spanStart.Equal(start) || !spanEnd.Equal(start.Add(2*time.Minute+10*time.Second)) {
+		t.Errorf("unexpected span %s - %s", spanStart, spanEnd)
+	}
+}
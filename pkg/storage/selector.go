@@ -0,0 +1,306 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InstanceLabel is the label instance ids are indexed under
+const InstanceLabel = "instance"
+
+// ExportedInstanceLabel holds the value of a user supplied instance label, like prometheus' exported_ labels
+const ExportedInstanceLabel = "exported_" + InstanceLabel
+
+// ExportLabels renames the user supplied labels conflicting with the labels set by the store,
+// returning a copy when a label was renamed
+func ExportLabels(labels map[string]string) map[string]string {
+	v, ok := labels[InstanceLabel]
+	if !ok {
+		return labels
+	}
+	ret := make(map[string]string, len(labels))
+	for k, v := range labels {
+		ret[k] = v
+	}
+	delete(ret, InstanceLabel)
+	ret[ExportedInstanceLabel] = v
+	return ret
+}
+
+type MatchType int
+
+const (
+	MatchEqual MatchType = iota
+	MatchNotEqual
+	MatchRegexp
+	MatchNotRegexp
+)
+
+func (m MatchType) String() string {
+	switch m {
+	case MatchEqual:
+		return "="
+	case MatchNotEqual:
+		return "!="
+	case MatchRegexp:
+		return "=~"
+	case MatchNotRegexp:
+		return "!~"
+	default:
+		return "unknown"
+	}
+}
+
+type Matcher struct {
+	Name  string
+	Type  MatchType
+	Value string
+
+	re *regexp.Regexp
+}
+
+func NewMatcher(name string, t MatchType, value string) (*Matcher, error) {
+	m := &Matcher{
+		Name:  name,
+		Type:  t,
+		Value: value,
+	}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		// regexes are fully anchored, like prometheus
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, err
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches reports whether the label value matches, missing labels have an empty value
+func (m *Matcher) Matches(value string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	case MatchNotRegexp:
+		return !m.re.MatchString(value)
+	default:
+		return false
+	}
+}
+
+func (m *Matcher) String() string {
+	return fmt.Sprintf("%s%s%s", m.Name, m.Type, strconv.Quote(m.Value))
+}
+
+// Selector is a set of matchers that must all match
+type Selector []*Matcher
+
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, m := range s {
+		if !m.Matches(labels[m.Name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesEmpty reports whether every matcher matches an empty value, such selectors match every instance
+func (s Selector) MatchesEmpty() bool {
+	for _, m := range s {
+		if !m.Matches("") {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	parts := make([]string, 0, len(s))
+	for _, m := range s {
+		parts = append(parts, m.String())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func isLabelChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '/' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// ParseSelector parses a prometheus style label selector, e.g. `{service="api", region=~"eu-.*"}`
+func ParseSelector(input string) (Selector, error) {
+	s := strings.TrimSpace(input)
+	if strings.HasPrefix(s, "{") {
+		if !strings.HasSuffix(s, "}") {
+			return nil, fmt.Errorf("invalid selector %q : missing closing brace", input)
+		}
+		s = s[1 : len(s)-1]
+	}
+	sel := Selector{}
+	for {
+		s = strings.TrimLeft(s, " \t\n")
+		if s == "" {
+			break
+		}
+		i := 0
+		for i < len(s) && isLabelChar(s[i]) {
+			i++
+		}
+		if i == 0 {
+			return nil, fmt.Errorf("invalid selector %q : expected label name at %q", input, s)
+		}
+		name := s[:i]
+		s = strings.TrimLeft(s[i:], " \t\n")
+
+		var t MatchType
+		switch {
+		case strings.HasPrefix(s, "=~"):
+			t, s = MatchRegexp, s[2:]
+		case strings.HasPrefix(s, "!~"):
+			t, s = MatchNotRegexp, s[2:]
+		case strings.HasPrefix(s, "!="):
+			t, s = MatchNotEqual, s[2:]
+		case strings.HasPrefix(s, "="):
+			t, s = MatchEqual, s[1:]
+		default:
+			return nil, fmt.Errorf("invalid selector %q : expected one of =, !=, =~, !~ after %s", input, name)
+		}
+		s = strings.TrimLeft(s, " \t\n")
+
+		value, rest, err := unquotePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q : %w", input, err)
+		}
+		m, err := NewMatcher(name, t, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q : %w", input, err)
+		}
+		sel = append(sel, m)
+
+		s = strings.TrimLeft(rest, " \t\n")
+		if s == "" {
+			break
+		}
+		if s[0] != ',' {
+			return nil, fmt.Errorf("invalid selector %q : expected ',' at %q", input, s)
+		}
+		s = s[1:]
+	}
+	return sel, nil
+}
+
+// unquotePrefix reads a double quoted string from the start of s
+func unquotePrefix(s string) (string, string, error) {
+	if s == "" || s[0] != '"' {
+		return "", "", fmt.Errorf("expected quoted label value at %q", s)
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			return value, s[i+1:], err
+		}
+	}
+	return "", "", fmt.Errorf("unterminated label value %q", s)
+}
+
+// LabelIndex maps label pairs to the instances they belong to.
+// LabelIndex is not safe for concurrent use, stores are expected to hold their own lock.
+type LabelIndex struct {
+	// id -> labels
+	labels map[string]map[string]string
+	// label name -> label value -> ids
+	postings map[string]map[string]map[string]struct{}
+}
+
+func NewLabelIndex() *LabelIndex {
+	return &LabelIndex{
+		labels:   map[string]map[string]string{},
+		postings: map[string]map[string]map[string]struct{}{},
+	}
+}
+
+// Set replaces the labels of an instance
+func (l *LabelIndex) Set(id string, labels map[string]string) {
+	l.Delete(id)
+	indexed := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		indexed[k] = v
+	}
+	indexed[InstanceLabel] = id
+	l.labels[id] = indexed
+	for k, v := range indexed {
+		if _, ok := l.postings[k]; !ok {
+			l.postings[k] = map[string]map[string]struct{}{}
+		}
+		if _, ok := l.postings[k][v]; !ok {
+			l.postings[k][v] = map[string]struct{}{}
+		}
+		l.postings[k][v][id] = struct{}{}
+	}
+}
+
+func (l *LabelIndex) Delete(id string) {
+	for k, v := range l.labels[id] {
+		delete(l.postings[k][v], id)
+		if len(l.postings[k][v]) == 0 {
+			delete(l.postings[k], v)
+		}
+		if len(l.postings[k]) == 0 {
+			delete(l.postings, k)
+		}
+	}
+	delete(l.labels, id)
+}
+
+// Labels returns the labels of an instance, without the instance label
+func (l *LabelIndex) Labels(id string) map[string]string {
+	ret := map[string]string{}
+	for k, v := range l.labels[id] {
+		if k != InstanceLabel {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+// Select returns the sorted ids of instances matching the selector
+func (l *LabelIndex) Select(sel Selector) []string {
+	var candidates map[string]struct{}
+	narrowed := false
+	// narrow down candidates using the postings of equality matchers
+	for _, m := range sel {
+		if m.Type != MatchEqual || m.Value == "" {
+			continue
+		}
+		ids := l.postings[m.Name][m.Value]
+		if !narrowed || len(ids) < len(candidates) {
+			candidates, narrowed = ids, true
+		}
+	}
+	ret := []string{}
+	if !narrowed {
+		for id, labels := range l.labels {
+			if sel.Matches(labels) {
+				ret = append(ret, id)
+			}
+		}
+	} else {
+		for id := range candidates {
+			if sel.Matches(l.labels[id]) {
+				ret = append(ret, id)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
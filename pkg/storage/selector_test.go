@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+		err      bool
+	}{
+		{input: `{}`, expected: `{}`},
+		{input: ``, expected: `{}`},
+		{input: `{service="api"}`, expected: `{service="api"}`},
+		{input: `service="api"`, expected: `{service="api"}`},
+		{input: `{ service = "api" , region=~"eu-.*", env!="dev", zone!~"b|c" }`, expected: `{service="api", region=~"eu-.*", env!="dev", zone!~"b|c"}`},
+		{input: `{path="a\"b,c"}`, expected: `{path="a\"b,c"}`},
+		{input: `{service="api"`, err: true},
+		{input: `{="api"}`, err: true},
+		{input: `{service:"api"}`, err: true},
+		{input: `{service=api}`, err: true},
+		{input: `{service="api}`, err: true},
+		{input: `{service="api" region="eu"}`, err: true},
+		{input: `{region=~"eu-("}`, err: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			sel, err := ParseSelector(tc.input)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error parsing %s, got %s", tc.input, sel)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sel.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, sel)
+			}
+		})
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"service": "api", "region": "eu-west"}
+	testCases := []struct {
+		selector string
+		matches  bool
+		empty    bool
+	}{
+		{selector: `{}`, matches: true, empty: true},
+		{selector: `{service="api"}`, matches: true},
+		{selector: `{service="db"}`},
+		{selector: `{service!="db"}`, matches: true, empty: true},
+		{selector: `{region=~"eu-.*"}`, matches: true},
+		// regexes are fully anchored
+		{selector: `{region=~"eu"}`},
+		{selector: `{region!~"us-.*"}`, matches: true, empty: true},
+		{selector: `{service="api", region=~"us-.*"}`},
+		// a missing label matches an empty value
+		{selector: `{zone=""}`, matches: true, empty: true},
+		{selector: `{zone=~".*"}`, matches: true, empty: true},
+		{selector: `{zone=~".+"}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			sel, err := ParseSelector(tc.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sel.Matches(labels); got != tc.matches {
+				t.Errorf("expected Matches to be %t, got %t", tc.matches, got)
+			}
+			if got := sel.MatchesEmpty(); got != tc.empty {
+				t.Errorf("expected MatchesEmpty to be %t, got %t", tc.empty, got)
+			}
+		})
+	}
+}
+
+func TestLabelIndex(t *testing.T) {
+	l := NewLabelIndex()
+	l.Set("a", map[string]string{"service": "api", "region": "eu-west"})
+	l.Set("b", map[string]string{"service": "api", "region": "us-east"})
+	l.Set("c", map[string]string{"service": "db", "region": "eu-west"})
+	// replaces the labels of c
+	l.Set("c", map[string]string{"service": "db", "region": "us-east"})
+
+	testCases := []struct {
+		selector string
+		expected []string
+	}{
+		{selector: `{}`, expected: []string{"a", "b", "c"}},
+		{selector: `{service="api"}`, expected: []string{"a", "b"}},
+		{selector: `{service="api", region="us-east"}`, expected: []string{"b"}},
+		{selector: `{region=~"eu-.*"}`, expected: []string{"a"}},
+		{selector: `{service!="api"}`, expected: []string{"c"}},
+		{selector: `{instance="b"}`, expected: []string{"b"}},
+		{selector: `{service="cache"}`, expected: []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			sel, err := ParseSelector(tc.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := l.Select(sel); !slices.Equal(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+
+	if labels := l.Labels("a"); len(labels) != 2 || labels[InstanceLabel] != "" {
+		t.Errorf("expected the labels of a without the instance label, got %v", labels)
+	}
+	l.Delete("a")
+	sel, _ := ParseSelector(`{region="eu-west"}`)
+	if got := l.Select(sel); len(got) != 0 {
+		t.Errorf("expected deleted instances not to be selected, got %v", got)
+	}
+}
+
+func TestExportLabels(t *testing.T) {
+	labels := map[string]string{"service": "api"}
+	if got := ExportLabels(labels); got["service"] != "api" || len(got) != 1 {
+		t.Errorf("expected labels without an instance label to be kept, got %v", got)
+	}
+	labels[InstanceLabel] = "10.0.0.1:6060"
+	got := ExportLabels(labels)
+	if got[InstanceLabel] != "" || got[ExportedInstanceLabel] != "10.0.0.1:6060" || got["service"] != "api" {
+		t.Errorf("expected the instance label to be exported, got %v", got)
+	}
+	if labels[InstanceLabel] != "10.0.0.1:6060" {
+		t.Error("expected the labels not to be modified")
+	}
+}
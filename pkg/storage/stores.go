@@ -17,4 +17,41 @@ type ProfileStore interface {
 		profile []*profile.Profile,
 	) error
 	Get(ctx context.Context, instanceId, profileType string, start, end time.Time) (*profile.Profile, error)
+	// Range returns the unmerged profiles overlapping the time range, sorted by start time.
+	// Returned profiles may be shared with the store and must not be modified.
+	Range(ctx context.Context, instanceId, profileType string, start, end time.Time) ([]*profile.Profile, error)
+	// Select merges the profiles of every instance whose labels match the selector
+	Select(ctx context.Context, selector Selector, profileType string, start, end time.Time) (*profile.Profile, error)
+	ListInstances(ctx context.Context) ([]InstanceInfo, error)
+	ListProfileTypes(ctx context.Context, instanceId string) ([]ProfileTypeInfo, error)
+	// Delete removes the profiles of every instance whose labels match the selector that overlap the time range,
+	// an empty profile type matches every type. Returns the number of removed profiles.
+	Delete(ctx context.Context, selector Selector, profileType string, start, end time.Time) (int, error)
+}
+
+type InstanceInfo struct {
+	Id     string
+	Labels map[string]string
+	// time range covered by the instance's stored profiles
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+type ProfileTypeInfo struct {
+	Type string
+	// time range covered by the stored profiles of this type
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Count     int
+}
+
+// EvictionStats tracks profiles dropped by a store to respect its memory budget
+type EvictionStats struct {
+	Profiles int64
+	Bytes    int64
+}
+
+// EvictionReporter is implemented by stores that evict profiles when they run out of memory
+type EvictionReporter interface {
+	Evicted() EvictionStats
 }